@@ -1,9 +1,11 @@
 package booker
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,12 +13,41 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"seat-killer/logging"
+	"seat-killer/redact"
+	"seat-killer/retry"
 )
 
 const (
-	bookURL = "https://hdu.huitu.zhishulib.com/Seat/Index/bookSeats?LAB_JSON=1"
+	// DefaultEndpoint is the library's booking API; see SetEndpoint.
+	DefaultEndpoint = "https://hdu.huitu.zhishulib.com" + BookPath + "?LAB_JSON=1"
+	// BookPath is the path of the booking API, also served by the mock server.
+	BookPath = "/Seat/Index/bookSeats"
+	// SSOHost is where the library sends an expired session to log in again.
+	SSOHost = "sso.hdu.edu.cn"
 )
 
+// bookURL is where BookSeat sends requests.
+var bookURL = DefaultEndpoint
+
+// SetEndpoint points BookSeat at another server, e.g. the local mock used by
+// dry runs. An empty url restores DefaultEndpoint.
+func SetEndpoint(url string) {
+	if url == "" {
+		url = DefaultEndpoint
+	}
+	bookURL = url
+}
+
+// Endpoint returns where BookSeat sends requests.
+func Endpoint() string {
+	return bookURL
+}
+
+// LoginPageMarkers identify the SSO login page when the library redirects an expired session to it.
+var LoginPageMarkers = []string{SSOHost, "login-page-flowkey", "统一身份认证"}
+
 // BookResponseData matches the structure of the booking response.
 type BookResponseData struct {
 	CODE    interface{} `json:"CODE"`
@@ -43,7 +74,10 @@ func getApiToken(apiTime string) string {
 }
 
 // BookSeat attempts to book a specific seat using the parameters from the request DTO.
-func BookSeat(req *BookingRequest) (*BookResponseData, error) {
+// A response that is not "ok" is returned together with a *BookingError describing why,
+// as classified by req.Classifier (DefaultClassifier when nil). When a 429 or 503
+// response says when to come back, the error carries that as a retry.RetryAfterError.
+func BookSeat(ctx context.Context, req *BookingRequest) (*BookResponseData, error) {
 	// The python script calculates beginTime from the beginning of the current day.
 	// The curl command uses a direct timestamp. Let's follow the curl command.
 	beginTimestamp := req.BeginTime.Unix()
@@ -57,7 +91,7 @@ func BookSeat(req *BookingRequest) (*BookResponseData, error) {
 	formData.Set("is_recommend", "1")
 	formData.Set("api_time", strconv.FormatInt(apiTimestamp, 10))
 
-	httpReq, err := http.NewRequest("POST", bookURL, strings.NewReader(formData.Encode()))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", bookURL, strings.NewReader(formData.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -69,30 +103,94 @@ func BookSeat(req *BookingRequest) (*BookResponseData, error) {
 	httpReq.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/142.0.0.0 Safari/537.36 Edg/142.0.0.0")
 	httpReq.Header.Set("Referer", "https://hdu.huitu.zhishulib.com/")
 
+	start := time.Now()
 	resp, err := req.Client.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	logging.From(ctx).Debug("Book request answered", "seat_id", req.SeatID, "status", resp.StatusCode, logging.Latency(time.Since(start)))
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
+	result, err := decodeBookResponse(ctx, req, resp, bodyBytes)
+	if after, ok := retryAfter(resp, time.Now()); ok && err != nil {
+		err = retry.WithRetryAfter(err, after)
+	}
+	return result, err
+}
+
+// decodeBookResponse turns a booking response into its data and, unless it is
+// "ok", the classified refusal.
+func decodeBookResponse(ctx context.Context, req *BookingRequest, resp *http.Response, bodyBytes []byte) (*BookResponseData, error) {
+	// An expired session gets redirected to the SSO login page.
+	if resp.Request != nil && resp.Request.URL.Host == SSOHost {
+		return nil, &BookingError{Kind: KindSessionExpired, Code: strconv.Itoa(resp.StatusCode), Message: "redirected to SSO login page"}
+	}
+
 	// Check for HTML response (often indicates server error like 502/503/504 or WAF block)
 	if len(bodyBytes) > 0 && bodyBytes[0] == '<' {
-		preview := string(bodyBytes)
-		if len(preview) > 100 {
-			preview = preview[:100] + "..."
+		for _, marker := range LoginPageMarkers {
+			if strings.Contains(string(bodyBytes), marker) {
+				return nil, &BookingError{Kind: KindSessionExpired, Code: strconv.Itoa(resp.StatusCode), Message: "server returned the SSO login page"}
+			}
 		}
-		return nil, fmt.Errorf("server returned HTML (likely error page): %s", preview)
+		return nil, &BookingError{Kind: KindBlocked, Code: strconv.Itoa(resp.StatusCode), Message: "server returned HTML (likely error page): " + redact.Preview(bodyBytes, 100)}
 	}
 
 	var bookData BookResponseData
 	if err := json.Unmarshal(bodyBytes, &bookData); err != nil {
-		return nil, fmt.Errorf("failed to decode book response: %w | Body: %s", err, string(bodyBytes))
+		return nil, fmt.Errorf("failed to decode book response: %w | Body: %s", err, redact.Preview(bodyBytes, 200))
 	}
 
-	return &bookData, nil
+	classifier := req.Classifier
+	if classifier == nil {
+		classifier = DefaultClassifier
+	}
+	logging.From(ctx).Debug("Book response decoded", "seat_id", req.SeatID, logging.Code(bookData.CODE), "message", bookData.MESSAGE)
+	return &bookData, classifier.Classify(&bookData)
+}
+
+// retryAfter reads the Retry-After header of a 429 or 503 response, given either
+// in seconds or as an HTTP date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// SessionProvider supplies the client of a logged-in session and can renew it.
+type SessionProvider interface {
+	Client() *http.Client
+	Relogin(ctx context.Context, stale *http.Client) error
+}
+
+// BookSeatWithSession books using the session's current client. When the server
+// reports that the session expired, it logs in again and replays the attempt once.
+func BookSeatWithSession(ctx context.Context, sess SessionProvider, req *BookingRequest) (*BookResponseData, error) {
+	attempt := *req
+	attempt.Client = sess.Client()
+	result, err := BookSeat(ctx, &attempt)
+	if !errors.Is(err, ErrSessionExpired) {
+		return result, err
+	}
+	if reloginErr := sess.Relogin(ctx, attempt.Client); reloginErr != nil {
+		return result, fmt.Errorf("%w; %v", err, reloginErr)
+	}
+	attempt.Client = sess.Client()
+	return BookSeat(ctx, &attempt)
 }
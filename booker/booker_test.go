@@ -0,0 +1,189 @@
+package booker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/redact"
+	"seat-killer/retry"
+)
+
+// roundTripFunc serves canned responses without a network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func cannedClient(status int, body string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), Request: req}, nil
+	})}
+}
+
+func TestBookSeatErrorsHideSecrets(t *testing.T) {
+	redact.AddSecret("uid-secret-42")
+	testCases := []struct {
+		name string
+		body string
+		kind ErrorKind
+	}{
+		{name: "无法解析的 JSON", body: `{"DATA": PHPSESSID=abc123 uid-secret-42 ` + strings.Repeat("x", 1000)},
+		{name: "WAF 页面", body: `<html>Set-Cookie: PHPSESSID=abc123</html>` + strings.Repeat("x", 1000), kind: KindBlocked},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &BookingRequest{Client: cannedClient(http.StatusOK, tc.body), UserID: "1", SeatID: 1, BeginTime: time.Now(), Duration: time.Hour}
+			_, err := BookSeat(context.Background(), req)
+			if err == nil {
+				t.Fatal("期望出现错误，但返回的错误为 nil")
+			}
+			if tc.kind != "" && !errors.Is(err, &BookingError{Kind: tc.kind}) {
+				t.Errorf("期望错误类型 %s, 实际为 %v", tc.kind, err)
+			}
+			msg := err.Error()
+			if strings.Contains(msg, "abc123") || strings.Contains(msg, "uid-secret-42") {
+				t.Errorf("错误信息泄露了敏感信息: %s", msg)
+			}
+			if len(msg) > 400 {
+				t.Errorf("错误信息未截断响应体, 长度 %d", len(msg))
+			}
+		})
+	}
+}
+
+// fakeSession hands out one client per login: clients[0] first, then one more per Relogin.
+type fakeSession struct {
+	clients    []*http.Client
+	reloginErr error
+	relogins   int
+}
+
+func (s *fakeSession) Client() *http.Client { return s.clients[s.relogins] }
+
+func (s *fakeSession) Relogin(_ context.Context, _ *http.Client) error {
+	if s.reloginErr != nil {
+		return s.reloginErr
+	}
+	s.relogins++
+	return nil
+}
+
+// answer serves one canned response; with redirect the response looks like it
+// came from the SSO host after following a redirect.
+func answer(body string, redirect bool, requests *int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requests++
+		final := req
+		if redirect {
+			final = req.Clone(req.Context())
+			final.URL, _ = url.Parse("https://" + SSOHost + "/login")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), Request: final}, nil
+	})}
+}
+
+func TestBookSeatWithSession(t *testing.T) {
+	const (
+		success = `{"CODE":"ok","MESSAGE":"预约成功","DATA":{"bookingId":"B1"}}`
+		taken   = `{"CODE":"1","MESSAGE":"该座位已被预约"}`
+	)
+	codeRule, err := NewClassifier([]Rule{{Kind: KindSessionExpired, Code: "401"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limitErr := errors.New("re-login limit reached (2)")
+
+	type response struct {
+		body     string
+		redirect bool
+	}
+	testCases := []struct {
+		name       string
+		first      response
+		replay     response
+		classifier *Classifier
+		reloginErr error
+		wantErr    error
+		wantRelog  int
+		wantReqs   int
+	}{
+		{name: "重定向到 SSO 后重新登录并重放", first: response{"<html>login</html>", true}, replay: response{body: success}, wantRelog: 1, wantReqs: 2},
+		{name: "登录页 HTML: SSO 域名", first: response{body: "<html><form action=\"https://" + SSOHost + "/login\"></html>"}, replay: response{body: success}, wantRelog: 1, wantReqs: 2},
+		{name: "登录页 HTML: flowkey", first: response{body: `<html><p id="login-page-flowkey">e1s1</p></html>`}, replay: response{body: success}, wantRelog: 1, wantReqs: 2},
+		{name: "登录页 HTML: 统一身份认证", first: response{body: "<html><title>统一身份认证</title></html>"}, replay: response{body: success}, wantRelog: 1, wantReqs: 2},
+		{name: "登录失效的提示信息", first: response{body: `{"CODE":"1","MESSAGE":"登录已过期"}`}, replay: response{body: success}, wantRelog: 1, wantReqs: 2},
+		{name: "按 CODE 配置的失效规则", first: response{body: `{"CODE":"401","MESSAGE":"unauthorized"}`}, replay: response{body: success}, classifier: codeRule, wantRelog: 1, wantReqs: 2},
+		{name: "重放后仍然失效只重新登录一次", first: response{"<html>login</html>", true}, replay: response{"<html>login</html>", true}, wantErr: ErrSessionExpired, wantRelog: 1, wantReqs: 2},
+		{name: "达到重新登录上限时不重放", first: response{"<html>login</html>", true}, reloginErr: limitErr, wantErr: ErrSessionExpired, wantReqs: 1},
+		{name: "普通错误页不视为登录失效", first: response{body: "<html>502 Bad Gateway</html>"}, wantErr: &BookingError{Kind: KindBlocked}, wantReqs: 1},
+		{name: "其他拒绝不重新登录", first: response{body: taken}, wantErr: ErrSeatTaken, wantReqs: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			sess := &fakeSession{
+				clients:    []*http.Client{answer(tc.first.body, tc.first.redirect, &requests), answer(tc.replay.body, tc.replay.redirect, &requests)},
+				reloginErr: tc.reloginErr,
+			}
+			req := &BookingRequest{UserID: "1", SeatID: 1, BeginTime: time.Now(), Duration: time.Hour, Classifier: tc.classifier}
+			result, err := BookSeatWithSession(context.Background(), sess, req)
+			if tc.wantErr == nil {
+				if err != nil || result == nil || result.DATA.BookingID != "B1" {
+					t.Fatalf("期望重放后预约成功, 实际为 %+v, %v", result, err)
+				}
+			} else if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("期望错误 %v, 实际为 %v", tc.wantErr, err)
+			}
+			if tc.reloginErr != nil && !strings.Contains(err.Error(), tc.reloginErr.Error()) {
+				t.Errorf("错误信息应包含重新登录失败的原因: %v", err)
+			}
+			if sess.relogins != tc.wantRelog || requests != tc.wantReqs {
+				t.Errorf("期望重新登录 %d 次、请求 %d 次, 实际为 %d 次、%d 次", tc.wantRelog, tc.wantReqs, sess.relogins, requests)
+			}
+		})
+	}
+}
+
+func TestBookSeatRetryAfter(t *testing.T) {
+	const tooFrequent = `{"CODE":"1","MESSAGE":"操作过于频繁"}`
+	testCases := []struct {
+		name       string
+		status     int
+		retryAfter string
+		wantHint   bool
+		want       time.Duration
+	}{
+		{name: "429 给出秒数", status: http.StatusTooManyRequests, retryAfter: "2", wantHint: true, want: 2 * time.Second},
+		{name: "503 给出日期", status: http.StatusServiceUnavailable, retryAfter: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), wantHint: true, want: time.Hour},
+		{name: "429 没有 Retry-After", status: http.StatusTooManyRequests},
+		{name: "无法解析的 Retry-After", status: http.StatusTooManyRequests, retryAfter: "soon"},
+		{name: "200 不看 Retry-After", status: http.StatusOK, retryAfter: "2"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				header := make(http.Header)
+				if tc.retryAfter != "" {
+					header.Set("Retry-After", tc.retryAfter)
+				}
+				return &http.Response{StatusCode: tc.status, Body: io.NopCloser(strings.NewReader(tooFrequent)), Header: header, Request: req}, nil
+			})}
+			_, err := BookSeat(context.Background(), &BookingRequest{Client: client, UserID: "42", SeatID: 1, Duration: time.Hour})
+			if !errors.Is(err, ErrTooFrequent) {
+				t.Fatalf("期望仍能识别为 too_frequent, 实际为 %v", err)
+			}
+			var hint retry.RetryAfterError
+			if got := errors.As(err, &hint); got != tc.wantHint {
+				t.Fatalf("期望带有重试提示=%v, 实际为 %v", tc.wantHint, got)
+			}
+			if tc.wantHint && (hint.RetryAfter() > tc.want || hint.RetryAfter() < tc.want-time.Minute) {
+				t.Errorf("期望提示约 %s 后重试, 实际为 %s", tc.want, hint.RetryAfter())
+			}
+		})
+	}
+}
@@ -12,4 +12,6 @@ type BookingRequest struct {
 	SeatID    int
 	BeginTime time.Time
 	Duration  time.Duration
+	// Classifier maps refusals to typed errors; DefaultClassifier is used when nil.
+	Classifier *Classifier
 }
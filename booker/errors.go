@@ -0,0 +1,127 @@
+package booker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies why the server refused a booking.
+type ErrorKind string
+
+const (
+	KindUnknown        ErrorKind = "unknown"
+	KindSeatTaken      ErrorKind = "seat_taken"
+	KindTooFrequent    ErrorKind = "too_frequent"
+	KindNotOpen        ErrorKind = "not_open"
+	KindAlreadyBooked  ErrorKind = "already_booked"
+	KindSessionExpired ErrorKind = "session_expired"
+	KindQuotaExceeded  ErrorKind = "quota_exceeded"
+	KindBlocked        ErrorKind = "blocked" // HTML/WAF page instead of JSON
+)
+
+var knownKinds = map[ErrorKind]bool{
+	KindUnknown: true, KindSeatTaken: true, KindTooFrequent: true, KindNotOpen: true,
+	KindAlreadyBooked: true, KindSessionExpired: true, KindQuotaExceeded: true, KindBlocked: true,
+}
+
+// BookingError is a server-side refusal mapped to an ErrorKind.
+type BookingError struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+}
+
+// Error implements the error interface.
+func (e *BookingError) Error() string {
+	return fmt.Sprintf("booking refused (%s): [%s] %s", e.Kind, e.Code, e.Message)
+}
+
+// Is matches any BookingError of the same kind, so callers can use errors.Is(err, booker.ErrSeatTaken).
+func (e *BookingError) Is(target error) bool {
+	t, ok := target.(*BookingError)
+	return ok && t.Kind == e.Kind
+}
+
+// Sentinels for errors.Is checks.
+var (
+	ErrSeatTaken      = &BookingError{Kind: KindSeatTaken}
+	ErrTooFrequent    = &BookingError{Kind: KindTooFrequent}
+	ErrNotOpen        = &BookingError{Kind: KindNotOpen}
+	ErrAlreadyBooked  = &BookingError{Kind: KindAlreadyBooked}
+	ErrSessionExpired = &BookingError{Kind: KindSessionExpired}
+	ErrQuotaExceeded  = &BookingError{Kind: KindQuotaExceeded}
+	ErrBlocked        = &BookingError{Kind: KindBlocked}
+)
+
+// Rule maps a CODE/MESSAGE pair to an ErrorKind. An empty Code matches any code;
+// an empty Contains matches any message. At least one of them must be set.
+type Rule struct {
+	Kind     ErrorKind
+	Code     string
+	Contains []string
+}
+
+func (r Rule) matches(code, message string) bool {
+	if r.Code != "" && r.Code != code {
+		return false
+	}
+	if len(r.Contains) == 0 {
+		return true
+	}
+	for _, fragment := range r.Contains {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRules reflect the wording observed from the library server. Order matters:
+// the first matching rule wins.
+var DefaultRules = []Rule{
+	{Kind: KindSessionExpired, Contains: []string{"未登录", "重新登录", "登录超时", "登录已过期", "会话"}},
+	{Kind: KindTooFrequent, Contains: []string{"频繁", "太快", "稍后再试"}},
+	{Kind: KindNotOpen, Contains: []string{"未开放", "尚未开始", "未到预约时间", "不在预约时间"}},
+	{Kind: KindSeatTaken, Contains: []string{"已被预约", "已被占用", "冲突", "不可预约"}},
+	{Kind: KindAlreadyBooked, Contains: []string{"已有预约", "已预约", "重复预约", "已经预约"}},
+	{Kind: KindQuotaExceeded, Contains: []string{"时长超过上限", "超过最大预约时长", "超出可预约时长", "预约次数已达上限", "超过预约次数"}},
+}
+
+// Classifier maps failed responses to BookingErrors.
+type Classifier struct {
+	rules []Rule
+}
+
+// DefaultClassifier uses DefaultRules only.
+var DefaultClassifier = &Classifier{rules: DefaultRules}
+
+// NewClassifier builds a classifier whose custom rules are checked before DefaultRules,
+// so a change in the server's wording can be handled from the config file.
+func NewClassifier(custom []Rule) (*Classifier, error) {
+	for i, rule := range custom {
+		if !knownKinds[rule.Kind] {
+			return nil, fmt.Errorf("response rule %d: unknown kind %q", i, rule.Kind)
+		}
+		if rule.Code == "" && len(rule.Contains) == 0 {
+			return nil, fmt.Errorf("response rule %d: either code or contains must be set", i)
+		}
+	}
+	rules := make([]Rule, 0, len(custom)+len(DefaultRules))
+	rules = append(rules, custom...)
+	rules = append(rules, DefaultRules...)
+	return &Classifier{rules: rules}, nil
+}
+
+// Classify returns nil for a successful response, otherwise a *BookingError.
+func (c *Classifier) Classify(r *BookResponseData) error {
+	if r.IsSuccess() {
+		return nil
+	}
+	code := fmt.Sprint(r.CODE)
+	for _, rule := range c.rules {
+		if rule.matches(code, r.MESSAGE) {
+			return &BookingError{Kind: rule.Kind, Code: code, Message: r.MESSAGE}
+		}
+	}
+	return &BookingError{Kind: KindUnknown, Code: code, Message: r.MESSAGE}
+}
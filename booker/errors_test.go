@@ -0,0 +1,61 @@
+package booker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	custom, err := NewClassifier([]Rule{{Kind: KindTooFrequent, Code: "429"}})
+	if err != nil {
+		t.Fatalf("NewClassifier 返回错误: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		classifier *Classifier
+		resp       BookResponseData
+		want       error
+	}{
+		{"成功", DefaultClassifier, BookResponseData{CODE: "ok", MESSAGE: "预约成功"}, nil},
+		{"座位已被预约", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "该座位已被预约"}, ErrSeatTaken},
+		{"请求过于频繁", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "操作过于频繁"}, ErrTooFrequent},
+		{"尚未开放", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "预约尚未开始"}, ErrNotOpen},
+		{"已有预约", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "您已有预约"}, ErrAlreadyBooked},
+		{"登录过期", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "请重新登录"}, ErrSessionExpired},
+		{"超出时长", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "预约时长超过上限"}, ErrQuotaExceeded},
+		{"超出次数", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "今日预约次数已达上限"}, ErrQuotaExceeded},
+		{"提到时长的其他错误", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "预约时长必须为整数小时"}, &BookingError{Kind: KindUnknown}},
+		{"提到超过的其他错误", DefaultClassifier, BookResponseData{CODE: "1", MESSAGE: "开始时间超过闭馆时间"}, &BookingError{Kind: KindUnknown}},
+		{"自定义 CODE 规则优先", custom, BookResponseData{CODE: float64(429), MESSAGE: "该座位已被预约"}, ErrTooFrequent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.classifier.Classify(&tc.resp)
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("期望 nil, 实际为 %v", got)
+				}
+				return
+			}
+			if !errors.Is(got, tc.want) {
+				t.Fatalf("期望 %v, 实际为 %v", tc.want, got)
+			}
+		})
+	}
+
+	var unknown *BookingError
+	if err := DefaultClassifier.Classify(&BookResponseData{CODE: "1", MESSAGE: "未知错误"}); !errors.As(err, &unknown) || unknown.Kind != KindUnknown {
+		t.Fatalf("期望 KindUnknown, 实际为 %v", err)
+	}
+}
+
+func TestNewClassifierRejectsInvalidRules(t *testing.T) {
+	if _, err := NewClassifier([]Rule{{Kind: "nonsense", Contains: []string{"x"}}}); err == nil {
+		t.Error("期望未知 kind 报错")
+	}
+	if _, err := NewClassifier([]Rule{{Kind: KindSeatTaken}}); err == nil {
+		t.Error("期望缺少 code 和 contains 时报错")
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"seat-killer/booker"
+	"seat-killer/config"
+	"seat-killer/dashboard"
+	"seat-killer/engine"
+	"seat-killer/logging"
+	"seat-killer/mapper"
+	"seat-killer/ratelimit"
+	"seat-killer/retry"
+	"seat-killer/transport"
+	"seat-killer/user"
+)
+
+// notOpenPause is how long dispatching pauses when booking has not opened yet.
+const notOpenPause = 250 * time.Millisecond
+
+// newLimiter builds the request budget of the booking attempts. With a store, the
+// budget is shared with every other run on this machine that uses it; without
+// one it covers this run only.
+func newLimiter(cfg config.RateLimitConfig, budget *ratelimit.Store) *ratelimit.Limiter {
+	limiterCfg := ratelimit.Config{
+		Rate:              cfg.Rate,
+		Burst:             cfg.Burst,
+		MinRate:           cfg.MinRate,
+		BackoffFactor:     cfg.BackoffFactor,
+		RecoveryPerSecond: cfg.RecoveryPerSecond,
+	}
+	if budget == nil {
+		return ratelimit.New(limiterCfg)
+	}
+	return ratelimit.NewShared(limiterCfg, budget)
+}
+
+// sharedBudget returns the store of the request budget that all accounts booking
+// from this machine share, keyed by the library's host.
+func sharedBudget() (*ratelimit.Store, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := url.Parse(booker.DefaultEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.HostStore(filepath.Join(dir, "ratelimit"), endpoint.Host), nil
+}
+
+// responseRules converts the configured classifier overrides into booker rules.
+func responseRules(cfgRules []config.ResponseRule) []booker.Rule {
+	rules := make([]booker.Rule, 0, len(cfgRules))
+	for _, r := range cfgRules {
+		rules = append(rules, booker.Rule{Kind: booker.ErrorKind(r.Kind), Code: r.Code, Contains: r.Contains})
+	}
+	return rules
+}
+
+// durationLadder tracks which of the acceptable durations to request next. A taken
+// seat only moves that seat down the ladder, as a shorter span of it may still be
+// free; a quota refusal is about the account, so it moves every seat down.
+type durationLadder struct {
+	candidates []time.Duration
+	next       map[string]int
+	// quota is the index of the longest duration the account's quota may still allow.
+	quota int
+}
+
+func newDurationLadder(candidates []time.Duration) *durationLadder {
+	return &durationLadder{candidates: candidates, next: make(map[string]int)}
+}
+
+// current returns the duration to request for a seat.
+func (l *durationLadder) current(seat string) time.Duration {
+	return l.candidates[max(l.next[seat], l.quota)]
+}
+
+// shrink moves a seat below the duration it was refused. It returns false when
+// none is left.
+func (l *durationLadder) shrink(seat string, refused time.Duration) bool {
+	below := slices.Index(l.candidates, refused) + 1
+	if below >= len(l.candidates) {
+		return false
+	}
+	l.next[seat] = max(l.next[seat], below)
+	return true
+}
+
+// shrinkQuota moves every seat below the duration the quota refused. It returns
+// false when none is left.
+func (l *durationLadder) shrinkQuota(refused time.Duration) bool {
+	below := slices.Index(l.candidates, refused) + 1
+	if below >= len(l.candidates) {
+		return false
+	}
+	l.quota = max(l.quota, below)
+	return true
+}
+
+// bookingTask carries what both phases share: the request parameters and what
+// was learned from earlier refusals. Attempts run concurrently, so the mutable
+// state is guarded by mu.
+type bookingTask struct {
+	session      booker.SessionProvider
+	account      string // metrics label of the account
+	loggedInUser *user.UserInfo
+	dayCfg       *config.DayConfig
+	classifier   *booker.Classifier
+	maxInFlight  int
+	limiter      *ratelimit.Limiter
+	// view shows every response on the dashboard; nil without one.
+	view *dashboard.Account
+
+	mu sync.Mutex
+	// phase labels the metrics of the attempts; phases run one after the other.
+	phase  string
+	ladder *durationLadder
+	// dropped holds seats the server reported as taken for every acceptable duration.
+	dropped map[string]bool
+	// accepted holds, per seat, the booking the server accepted. Several attempts can
+	// succeed before the others are cancelled; the user holds all of them.
+	accepted map[string]acceptedBooking
+}
+
+// acceptedBooking is a booking the server accepted.
+type acceptedBooking struct {
+	seat     string
+	duration time.Duration
+	id       string
+}
+
+// phaseOutcome reports how a booking phase ended.
+type phaseOutcome struct {
+	success   bool
+	seat      string
+	duration  time.Duration
+	bookingID string
+	// also lists the bookings accepted for other seats before their attempts could
+	// be cancelled, in priority order. The account holds these too.
+	also []acceptedBooking
+	// stopReason is set when retrying is pointless, e.g. the account already holds a booking.
+	stopReason error
+}
+
+// executeBookingPhase runs the concurrent booking engine for a specific time window and seat strategy.
+// The phase's requests are cancelled when ctx is done or end is reached.
+func executeBookingPhase(ctx context.Context, task *bookingTask, phase string, start, end time.Time, primaryOnly bool) phaseOutcome {
+	dayCfg := task.dayCfg
+	logger := logging.From(ctx)
+	candidates := dayCfg.Seats
+	if primaryOnly {
+		candidates = dayCfg.Seats[:1]
+	}
+	var seats []string
+	task.mu.Lock()
+	task.phase = phase
+	for _, seatNum := range candidates {
+		if !task.dropped[seatNum] {
+			seats = append(seats, seatNum)
+		}
+	}
+	task.mu.Unlock()
+	if len(seats) == 0 {
+		logger.Info("No seats left to try in this phase")
+		return phaseOutcome{}
+	}
+
+	if primaryOnly {
+		logger.Info("Entering phase, focusing on the primary seat", logging.Seat(seats[0]))
+	} else {
+		logger.Info("Entering phase, trying all seats", "seats", len(seats))
+	}
+
+	if retry.Sleep(ctx, time.Until(start)) != nil {
+		return phaseOutcome{}
+	}
+	ctx, cancel := context.WithDeadline(ctx, end)
+	defer cancel()
+
+	eng := &engine.Engine{MaxInFlight: task.maxInFlight, Limiter: task.limiter}
+	report := eng.Run(ctx, seats, task.attempt)
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	winner := report.Winner
+	if winner == "" {
+		// An attempt can be accepted while another one stops the run.
+		for _, seatNum := range dayCfg.Seats {
+			if _, ok := task.accepted[seatNum]; ok {
+				winner = seatNum
+				break
+			}
+		}
+	}
+	if winner != "" {
+		won := task.accepted[winner]
+		outcome := phaseOutcome{success: true, seat: winner, duration: won.duration, bookingID: won.id}
+		for _, seatNum := range dayCfg.Seats {
+			if other, ok := task.accepted[seatNum]; ok && seatNum != winner {
+				outcome.also = append(outcome.also, other)
+			}
+		}
+		return outcome
+	}
+	if report.Err != nil {
+		return phaseOutcome{stopReason: report.Err}
+	}
+	return phaseOutcome{}
+}
+
+// attempt books one seat once and reacts to the kind of refusal: a taken seat is
+// dropped (after trying shorter durations), throttling and WAF pages back off,
+// "not open yet" waits for the next round, and an existing booking or a session
+// that could not be renewed stops the run.
+func (task *bookingTask) attempt(ctx context.Context, seatNum string) engine.Result {
+	dayCfg := task.dayCfg
+	logger := logging.From(ctx).With(logging.Seat(seatNum))
+
+	seatID, err := mapper.GetSeatID(dayCfg.Name, seatNum)
+	if err != nil {
+		logger.Warn("Seat not found in room, skipping", logging.Err(err))
+		task.view.Seat(seatNum, dashboard.SeatResult{Message: "not in the seat map"})
+		task.drop(seatNum)
+		return engine.Result{Drop: true}
+	}
+
+	task.mu.Lock()
+	duration := task.ladder.current(seatNum)
+	task.mu.Unlock()
+
+	logger = logger.With("seat_id", seatID, "duration", config.Duration(duration).String())
+	logger.Info("Attempting to book")
+	var result *booker.BookResponseData
+	bookReq := &booker.BookingRequest{
+		UserID:     task.loggedInUser.UID,
+		SeatID:     seatID,
+		BeginTime:  dayCfg.BeginTime(time.Now().AddDate(0, 0, 2)),
+		Duration:   duration,
+		Classifier: task.classifier,
+	}
+	var timing *transport.Timing
+	start := time.Now()
+	bookFunc := func() error {
+		var bookErr error
+		var traceCtx context.Context
+		traceCtx, timing = transport.WithTiming(ctx)
+		requestStart := time.Now()
+		// Expired sessions are renewed and the attempt replayed transparently.
+		result, bookErr = booker.BookSeatWithSession(traceCtx, task.session, bookReq)
+		requestDuration.Observe(time.Since(requestStart).Seconds(), "book")
+		return bookErr
+	}
+
+	err = bookingPolicy.Do(ctx, bookFunc)
+	task.mu.Lock()
+	phase := task.phase
+	task.mu.Unlock()
+	outcome, code := bookingOutcome(result, err)
+	bookingAttempts.Inc(task.account, phase, outcome, code)
+	if err == nil {
+		task.view.Seat(seatNum, dashboard.SeatResult{Code: code, Message: result.MESSAGE, Latency: time.Since(start), Booked: true})
+		logger.Info("Booking accepted", logging.Code(result.CODE), "message", result.MESSAGE, logging.Latency(time.Since(start)), "timing", timing.String())
+		task.mu.Lock()
+		task.accepted[seatNum] = acceptedBooking{seat: seatNum, duration: duration, id: result.DATA.BookingID}
+		task.mu.Unlock()
+		return engine.Result{Success: true}
+	}
+	if ctx.Err() != nil {
+		return engine.Result{} // Cancelled: another seat won or the window closed.
+	}
+	// Log the final error after retries, but don't stop the whole process.
+	var refusal *booker.BookingError
+	message := err.Error()
+	if errors.As(err, &refusal) {
+		logger = logger.With(logging.Code(refusal.Code), "kind", string(refusal.Kind))
+		message = refusal.Message
+	}
+	task.view.Seat(seatNum, dashboard.SeatResult{Code: code, Message: message, Latency: time.Since(start)})
+	logger.Warn("Booking attempt failed", logging.Err(err), logging.Latency(time.Since(start)), "timing", timing.String())
+
+	switch {
+	case errors.Is(err, booker.ErrSeatTaken), errors.Is(err, booker.ErrQuotaExceeded):
+		// Both may be caused by the span being too long; try a shorter one first.
+		// The quota is the account's, so the other seats skip the refused duration too.
+		task.mu.Lock()
+		var shrunk bool
+		if errors.Is(err, booker.ErrQuotaExceeded) {
+			shrunk = task.ladder.shrinkQuota(duration)
+		} else {
+			shrunk = task.ladder.shrink(seatNum, duration)
+		}
+		next := task.ladder.current(seatNum)
+		task.mu.Unlock()
+		if shrunk {
+			logger.Info("Duration rejected, shrinking", "next_duration", config.Duration(next).String())
+			return engine.Result{}
+		}
+		if errors.Is(err, booker.ErrQuotaExceeded) {
+			return engine.Result{Stop: err}
+		}
+		logger.Info("Seat is taken, moving on to the next seat")
+		task.drop(seatNum)
+		return engine.Result{Drop: true}
+	case errors.Is(err, booker.ErrTooFrequent), errors.Is(err, booker.ErrBlocked):
+		logger.Warn("Server is throttling us, slowing down")
+		task.limiter.Backoff()
+		return engine.Result{}
+	case errors.Is(err, booker.ErrNotOpen):
+		// Booking has not opened yet; restart from the primary seat after a short pause.
+		return engine.Result{Backoff: notOpenPause}
+	case errors.Is(err, booker.ErrAlreadyBooked), errors.Is(err, booker.ErrSessionExpired):
+		return engine.Result{Stop: err}
+	}
+	return engine.Result{}
+}
+
+func (task *bookingTask) drop(seatNum string) {
+	task.mu.Lock()
+	task.dropped[seatNum] = true
+	task.mu.Unlock()
+	task.view.DropSeat(seatNum)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/booker"
+	"seat-killer/config"
+	"seat-killer/engine"
+	"seat-killer/history"
+	"seat-killer/mapper"
+	"seat-killer/user"
+)
+
+// fakeSession hands out a client that never needs renewing.
+type fakeSession struct{ client *http.Client }
+
+func (s *fakeSession) Client() *http.Client                              { return s.client }
+func (s *fakeSession) Relogin(ctx context.Context, _ *http.Client) error { return nil }
+
+// bookingServer answers booking requests through reply, which receives the requested duration.
+func bookingServer(reply func(duration time.Duration) string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.ParseForm(); err != nil {
+			return nil, err
+		}
+		seconds, _ := time.ParseDuration(req.PostForm.Get("duration") + "s")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(reply(seconds))),
+			Request:    req,
+		}, nil
+	})}
+}
+
+func TestAttemptShrinksDurationOnRefusal(t *testing.T) {
+	seatMapPath := filepath.Join(t.TempDir(), "seat_map.txt")
+	if err := os.WriteFile(seatMapPath, []byte("# Room: 一楼\nSeatID: 101, Title: 35\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mapper.LoadSeatMap(seatMapPath); err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		taken   = `{"CODE":"1","MESSAGE":"该座位已被预约"}`
+		quota   = `{"CODE":"1","MESSAGE":"预约时长超过上限"}`
+		success = `{"CODE":"ok","MESSAGE":"预约成功","DATA":{"bookingId":"B42"}}`
+	)
+	testCases := []struct {
+		name string
+		// reply answers a request for the given duration.
+		reply        func(time.Duration) string
+		want         []engine.Result
+		wantErr      error
+		wantDropped  bool
+		wantObtained time.Duration
+	}{
+		{
+			name:        "座位被占用时逐级缩短, 最短时长仍被占用则放弃该座位",
+			reply:       func(time.Duration) string { return taken },
+			want:        []engine.Result{{}, {}, {Drop: true}},
+			wantDropped: true,
+		},
+		{
+			name: "超出时长上限时缩短后预约成功",
+			reply: func(d time.Duration) string {
+				if d > 4*time.Hour {
+					return quota
+				}
+				return success
+			},
+			want:         []engine.Result{{}, {}, {Success: true}},
+			wantObtained: 4 * time.Hour,
+		},
+		{
+			name:    "最短时长仍超出上限时停止",
+			reply:   func(time.Duration) string { return quota },
+			want:    []engine.Result{{}, {}, {}},
+			wantErr: booker.ErrQuotaExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &bookingTask{
+				session:      &fakeSession{client: bookingServer(tc.reply)},
+				account:      "test",
+				loggedInUser: &user.UserInfo{UID: "42"},
+				dayCfg:       &config.DayConfig{Name: "一楼", Seats: []string{"35"}},
+				classifier:   booker.DefaultClassifier,
+				phase:        "attack",
+				ladder:       newDurationLadder([]time.Duration{12 * time.Hour, 8 * time.Hour, 4 * time.Hour}),
+				dropped:      make(map[string]bool),
+				accepted:     make(map[string]acceptedBooking),
+			}
+			for i, want := range tc.want {
+				got := task.attempt(context.Background(), "35")
+				if i == len(tc.want)-1 && tc.wantErr != nil {
+					if !errors.Is(got.Stop, tc.wantErr) {
+						t.Fatalf("第 %d 次尝试期望停止于 %v, 实际为 %+v", i+1, tc.wantErr, got)
+					}
+					continue
+				}
+				if got != want {
+					t.Fatalf("第 %d 次尝试期望 %+v, 实际为 %+v", i+1, want, got)
+				}
+			}
+			if task.dropped["35"] != tc.wantDropped {
+				t.Errorf("期望 dropped=%v, 实际为 %v", tc.wantDropped, task.dropped["35"])
+			}
+			if got := task.accepted["35"]; got.duration != tc.wantObtained || (tc.wantObtained != 0 && got.id != "B42") {
+				t.Errorf("期望预约时长 %v、预约 ID B42, 实际为 %+v", tc.wantObtained, got)
+			}
+		})
+	}
+}
+
+func TestQuotaRefusalShrinksEverySeat(t *testing.T) {
+	seatMapPath := filepath.Join(t.TempDir(), "seat_map.txt")
+	if err := os.WriteFile(seatMapPath, []byte("# Room: 一楼\nSeatID: 101, Title: 35\nSeatID: 102, Title: 36\nSeatID: 103, Title: 37\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mapper.LoadSeatMap(seatMapPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var requested []time.Duration
+	task := &bookingTask{
+		session: &fakeSession{client: bookingServer(func(d time.Duration) string {
+			requested = append(requested, d)
+			switch {
+			case d > 8*time.Hour:
+				return `{"CODE":"1","MESSAGE":"预约时长超过上限"}`
+			case len(requested) == 2:
+				return `{"CODE":"1","MESSAGE":"该座位已被预约"}`
+			}
+			return `{"CODE":"ok","MESSAGE":"预约成功","DATA":{"bookingId":"B42"}}`
+		})},
+		account:      "test",
+		loggedInUser: &user.UserInfo{UID: "42"},
+		dayCfg:       &config.DayConfig{Name: "一楼", Seats: []string{"35", "36", "37"}},
+		classifier:   booker.DefaultClassifier,
+		phase:        "attack",
+		ladder:       newDurationLadder([]time.Duration{12 * time.Hour, 8 * time.Hour, 4 * time.Hour}),
+		dropped:      make(map[string]bool),
+		accepted:     make(map[string]acceptedBooking),
+	}
+	// The quota refuses 12h on seat 35, so seat 36 starts at 8h. Seat 36 being taken
+	// at 8h only shrinks seat 36; seat 37 still asks for 8h.
+	for _, seat := range []string{"35", "36", "37"} {
+		if got := task.attempt(context.Background(), seat); got.Stop != nil || got.Drop {
+			t.Fatalf("座位 %s 期望继续尝试, 实际为 %+v", seat, got)
+		}
+	}
+	if want := []time.Duration{12 * time.Hour, 8 * time.Hour, 8 * time.Hour}; !slices.Equal(requested, want) {
+		t.Errorf("期望超出配额后所有座位都从缩短后的时长开始, 请求的时长为 %v, 期望 %v", requested, want)
+	}
+	if got := task.ladder.current("36"); got != 4*time.Hour {
+		t.Errorf("座位 36 被占用后期望缩短至 4h, 实际为 %s", got)
+	}
+	if got := task.accepted["37"]; got.duration != 8*time.Hour {
+		t.Errorf("期望座位 37 以 8h 预约成功, 实际为 %+v", got)
+	}
+}
+
+func TestExecuteBookingPhaseReportsEveryAcceptedBooking(t *testing.T) {
+	seatMapPath := filepath.Join(t.TempDir(), "seat_map.txt")
+	if err := os.WriteFile(seatMapPath, []byte("# Room: 一楼\nSeatID: 101, Title: 35\nSeatID: 102, Title: 36\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mapper.LoadSeatMap(seatMapPath); err != nil {
+		t.Fatal(err)
+	}
+	// Both seats are accepted while the other request is still in flight.
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.ParseForm(); err != nil {
+			return nil, err
+		}
+		time.Sleep(20 * time.Millisecond)
+		body := `{"CODE":"ok","MESSAGE":"预约成功","DATA":{"bookingId":"B` + req.PostForm.Get("seats[0]") + `"}}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), Request: req}, nil
+	})}
+	ids := map[string]string{"35": "B101", "36": "B102"}
+
+	for range 5 {
+		task := &bookingTask{
+			session:      &fakeSession{client: client},
+			account:      "test",
+			loggedInUser: &user.UserInfo{UID: "42"},
+			dayCfg:       &config.DayConfig{Name: "一楼", Seats: []string{"35", "36"}},
+			classifier:   booker.DefaultClassifier,
+			maxInFlight:  2,
+			limiter:      newLimiter(config.RateLimitConfig{Rate: 100, Burst: 10}, nil),
+			ladder:       newDurationLadder([]time.Duration{4 * time.Hour}),
+			dropped:      make(map[string]bool),
+			accepted:     make(map[string]acceptedBooking),
+		}
+		now := time.Now()
+		outcome := executeBookingPhase(context.Background(), task, "fallback", now, now.Add(time.Second), false)
+		if !outcome.success || outcome.bookingID != ids[outcome.seat] || outcome.duration != 4*time.Hour {
+			t.Fatalf("报告的预约应属于获胜的座位, 实际为 %+v", outcome)
+		}
+		other := "36"
+		if outcome.seat == "36" {
+			other = "35"
+		}
+		want := []acceptedBooking{{seat: other, duration: 4 * time.Hour, id: ids[other]}}
+		if !slices.Equal(outcome.also, want) {
+			t.Fatalf("同时被接受的另一个座位也应报告, 期望 %+v, 实际为 %+v", want, outcome.also)
+		}
+	}
+}
+
+func TestRunStatusExtras(t *testing.T) {
+	status := &runStatus{}
+	status.record(func(e *history.Entry) {
+		e.Account, e.Weekday, e.Room, e.Outcome, e.Seat = "23****01", "周一", "一楼", history.Booked, "35"
+	})
+	status.also(history.Entry{Outcome: history.Booked, Seat: "36", BookingID: "B102"})
+	started := time.Date(2026, 10, 19, 20, 0, 0, 0, time.Local)
+
+	extras := status.extras(started)
+	if len(extras) != 1 {
+		t.Fatalf("期望 1 条额外预约, 实际为 %d 条", len(extras))
+	}
+	if e := extras[0]; e.Seat != "36" || e.BookingID != "B102" || e.Account != "23****01" || e.Room != "一楼" || !e.Time.Equal(started) {
+		t.Errorf("额外预约应带上本次运行的账号、房间和时间, 实际为 %+v", e)
+	}
+	status.reset()
+	if extras := status.extras(started); len(extras) != 0 {
+		t.Errorf("新的运行不应保留上次的额外预约, 实际为 %+v", extras)
+	}
+}
@@ -0,0 +1,216 @@
+package breaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// Closed lets every request through and counts consecutive failures.
+	Closed State = iota
+	// Open rejects every request until OpenTimeout has passed.
+	Open
+	// HalfOpen lets a limited number of probe requests through to test recovery.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Config holds the thresholds of a breaker.
+type Config struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing again.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is how many probes may be in flight while half-open; that many
+	// consecutive successes close the breaker again.
+	HalfOpenProbes int
+	// Now returns the current time. Defaults to time.Now; tests inject a fake clock.
+	Now func() time.Time
+}
+
+// OpenError is returned for requests rejected by an open breaker.
+type OpenError struct {
+	Host  string
+	Until time.Time
+	now   func() time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open until %s", e.Host, e.Until.Format("15:04:05.000"))
+}
+
+// RetryAfter tells retry policies when the breaker will let a probe through.
+func (e *OpenError) RetryAfter() time.Duration {
+	return max(e.Until.Sub(e.now()), 0)
+}
+
+// Stats is a point-in-time view of a breaker for logs and metrics.
+type Stats struct {
+	State State
+	// ConsecutiveFailures in the current closed period.
+	ConsecutiveFailures int
+	// Opens counts transitions into Open.
+	Opens int
+	// Rejected counts requests refused while open.
+	Rejected int
+}
+
+// Breaker guards a single host.
+type Breaker struct {
+	host     string
+	cfg      Config
+	onChange func(host string, from, to State)
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	successes int
+	probes    int
+	openedAt  time.Time
+	opens     int
+	rejected  int
+}
+
+// Allow reports whether a request may proceed. Every allowed request must be
+// followed by exactly one call to Record or Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open {
+		until := b.openedAt.Add(b.cfg.OpenTimeout)
+		if b.cfg.Now().Before(until) {
+			b.rejected++
+			return &OpenError{Host: b.host, Until: until, now: b.cfg.Now}
+		}
+		b.transition(HalfOpen)
+	}
+	if b.state == HalfOpen {
+		if b.probes >= b.cfg.HalfOpenProbes {
+			b.rejected++
+			return &OpenError{Host: b.host, Until: b.cfg.Now(), now: b.cfg.Now}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed request.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.transition(Open)
+		}
+	case HalfOpen:
+		b.probes--
+		if !success {
+			b.transition(Open)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenProbes {
+			b.transition(Closed)
+		}
+	}
+}
+
+// Release gives back an allowed request whose outcome says nothing about the
+// host's health, such as one cancelled by the caller.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// transition switches state. Callers hold mu.
+func (b *Breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.failures, b.successes, b.probes = 0, 0, 0
+	if to == Open {
+		b.openedAt = b.cfg.Now()
+		b.opens++
+	}
+	if b.onChange != nil {
+		b.onChange(b.host, from, to)
+	}
+}
+
+// Stats returns the breaker's current counters.
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{State: b.state, ConsecutiveFailures: b.failures, Opens: b.opens, Rejected: b.rejected}
+}
+
+// Set holds one breaker per host.
+type Set struct {
+	cfg Config
+	// OnStateChange, when set before use, is called on every transition.
+	// It runs with the breaker locked and must not call back into it.
+	OnStateChange func(host string, from, to State)
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewSet creates an empty set; breakers are created on first use of a host.
+func NewSet(cfg Config) *Set {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Set{cfg: cfg, breakers: make(map[string]*Breaker)}
+}
+
+// For returns the breaker for host.
+func (s *Set) For(host string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.breakers[host]
+	if !ok {
+		b = &Breaker{host: host, cfg: s.cfg, onChange: s.OnStateChange}
+		s.breakers[host] = b
+	}
+	return b
+}
+
+// Stats returns the stats of every known host.
+func (s *Set) Stats() map[string]Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]Stats, len(s.breakers))
+	for host, b := range s.breakers {
+		stats[host] = b.Stats()
+	}
+	return stats
+}
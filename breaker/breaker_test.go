@@ -0,0 +1,174 @@
+package breaker
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestSet(clock *fakeClock) (*Set, *[]string) {
+	var transitions []string
+	s := NewSet(Config{FailureThreshold: 3, OpenTimeout: 10 * time.Second, HalfOpenProbes: 1, Now: clock.now})
+	s.OnStateChange = func(host string, from, to State) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}
+	return s, &transitions
+}
+
+func TestBreakerStateMachine(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC)}
+	set, transitions := newTestSet(clock)
+	b := set.For("library")
+
+	for i := 0; i < 3; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("第 %d 次请求不应被拒绝: %v", i+1, err)
+		}
+		b.Record(false)
+	}
+	err := b.Allow()
+	var open *OpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("连续失败 3 次后期望熔断, 实际为 %v", err)
+	}
+	if got := open.RetryAfter(); got != 10*time.Second {
+		t.Errorf("期望 RetryAfter 为 10s, 实际为 %s", got)
+	}
+
+	clock.advance(10 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("超时后期望放行一个探测请求: %v", err)
+	}
+	if err := b.Allow(); err == nil {
+		t.Fatal("半开状态下期望只放行一个探测请求")
+	}
+	b.Record(false)
+	if b.Stats().State != Open {
+		t.Fatalf("探测失败后期望重新熔断, 实际为 %s", b.Stats().State)
+	}
+
+	clock.advance(10 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("再次超时后期望放行探测请求: %v", err)
+	}
+	b.Record(true)
+
+	stats := b.Stats()
+	if stats.State != Closed || stats.Opens != 2 || stats.Rejected != 2 {
+		t.Errorf("期望恢复为 closed 且熔断 2 次、拒绝 2 次, 实际为 %+v", stats)
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(*transitions) != len(want) {
+		t.Fatalf("期望状态变化 %v, 实际为 %v", want, *transitions)
+	}
+	for i := range want {
+		if (*transitions)[i] != want[i] {
+			t.Fatalf("期望状态变化 %v, 实际为 %v", want, *transitions)
+		}
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	set, _ := newTestSet(clock)
+	b := set.For("sso")
+	for i := 0; i < 10; i++ {
+		b.Allow()
+		b.Record(i%2 == 0)
+	}
+	if b.Stats().State != Closed {
+		t.Errorf("非连续失败不应熔断")
+	}
+}
+
+func TestTransportTripsOnHTMLErrorPages(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>blocked</html>"))
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{t: time.Now()}
+	set, _ := newTestSet(clock)
+	client := &http.Client{Transport: &Transport{Base: http.DefaultTransport, Set: set}}
+
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+		req.Header.Set("Accept", "application/json, text/plain, */*")
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+	if calls != 3 {
+		t.Errorf("期望熔断后不再请求服务器 (3 次), 实际请求了 %d 次", calls)
+	}
+}
+
+func TestTransportIgnoresTheLoginPage(t *testing.T) {
+	const loginPage = `<html><input name="login-page-flowkey"></html>`
+	sso := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>统一身份认证</html>"))
+	}))
+	defer sso.Close()
+	calls := 0
+	library := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, sso.URL+"/login", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(loginPage))
+	}))
+	defer library.Close()
+
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{"重定向到 SSO 登录页", "/redirect"},
+		{"直接返回登录页", "/page"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = 0
+			clock := &fakeClock{t: time.Now()}
+			set, transitions := newTestSet(clock)
+			client := &http.Client{Transport: &Transport{
+				Base:             http.DefaultTransport,
+				Set:              set,
+				LoginHost:        strings.TrimPrefix(sso.URL, "http://"),
+				LoginPageMarkers: []string{"login-page-flowkey", "统一身份认证"},
+			}}
+			for i := 0; i < 5; i++ {
+				req, _ := http.NewRequest(http.MethodPost, library.URL+tc.path, nil)
+				req.Header.Set("Accept", "application/json, text/plain, */*")
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("第 %d 次请求失败: %v", i+1, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if !strings.Contains(string(body), "<html>") {
+					t.Fatalf("检查后应保留完整的页面, 实际为 %q", body)
+				}
+			}
+			if calls != 5 || len(*transitions) != 0 {
+				t.Errorf("登录页不应触发熔断, 实际请求 %d 次, 状态变化 %v", calls, *transitions)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package breaker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// loginPagePeek bounds how much of an HTML response is searched for LoginPageMarkers.
+const loginPagePeek = 64 << 10
+
+// Transport is an http.RoundTripper that routes every request through the
+// breaker of its host.
+type Transport struct {
+	Base http.RoundTripper
+	Set  *Set
+	// LoginHost is the SSO host and LoginPageMarkers identify its login page. The
+	// library reports an expired session by redirecting there or by serving that
+	// page, which says nothing about either host's health, so neither is a failure.
+	LoginHost        string
+	LoginPageMarkers []string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.Set.For(req.URL.Host)
+	if err := b.Allow(); err != nil {
+		return nil, err
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		// Our own cancellation says nothing about the host's health.
+		b.Release()
+		return resp, err
+	}
+	b.Record(err == nil && !t.isErrorPage(req, resp))
+	return resp, err
+}
+
+// isErrorPage reports responses that indicate an unhealthy or blocking host:
+// server errors, WAF-style refusals, and HTML other than the login page served
+// to a request that asked for JSON.
+func (t *Transport) isErrorPage(req *http.Request, resp *http.Response) bool {
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	}
+	wantsJSON := strings.HasPrefix(req.Header.Get("Accept"), "application/json")
+	if !wantsJSON || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return false
+	}
+	return !t.isLoginPage(req, resp)
+}
+
+// isLoginPage reports whether resp is the SSO login page: either a redirect led
+// to LoginHost, or the body carries one of LoginPageMarkers. The part of the body
+// that is searched is put back for the caller.
+func (t *Transport) isLoginPage(req *http.Request, resp *http.Response) bool {
+	if t.LoginHost != "" && req.Response != nil && req.URL.Host == t.LoginHost {
+		return true
+	}
+	if len(t.LoginPageMarkers) == 0 || resp.Body == nil {
+		return false
+	}
+	peek, _ := io.ReadAll(io.LimitReader(resp.Body, loginPagePeek))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+	for _, marker := range t.LoginPageMarkers {
+		if bytes.Contains(peek, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
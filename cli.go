@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"seat-killer/dashboard"
+	"seat-killer/history"
+	"seat-killer/logging"
+	"seat-killer/redact"
+)
+
+// Default file names inside the config directory; the history lives in the state directory.
+const (
+	userInfoFile   = "user_info.yml"
+	seatConfigFile = "user_config.yml"
+	seatMapFile    = "seat_report.txt"
+	historyFile    = "history.jsonl"
+)
+
+// configPaths locates the files a command works with. All paths are absolute.
+type configPaths struct {
+	// dir is the config directory. Relative paths inside the configs, such as the
+	// session cache, the log directory or a password_ref file, are resolved against it.
+	dir        string
+	userInfo   string
+	seatConfig string
+	seatMap    string
+	// history is the run history of this config directory, kept in the state
+	// directory (see historyPath).
+	history string
+}
+
+// resolvePaths applies the defaults to the global flags. Without -config-dir, the
+// current directory is used when it holds user_config.yml, so existing setups keep
+// working; otherwise $XDG_CONFIG_HOME/seat-killer (usually ~/.config/seat-killer).
+// The run history goes to the state directory whatever the config directory is.
+func resolvePaths(configDir, userInfo, seatMap string) (configPaths, error) {
+	if configDir == "" {
+		if _, err := os.Stat(seatConfigFile); err == nil {
+			configDir = "."
+		} else {
+			base, err := os.UserConfigDir()
+			if err != nil {
+				return configPaths{}, fmt.Errorf("cannot determine the config directory, use -config-dir: %w", err)
+			}
+			configDir = filepath.Join(base, "seat-killer")
+		}
+	}
+	dir, err := filepath.Abs(configDir)
+	if err != nil {
+		return configPaths{}, err
+	}
+	state, err := stateDir()
+	if err != nil {
+		return configPaths{}, fmt.Errorf("cannot determine the state directory, set XDG_STATE_HOME: %w", err)
+	}
+	paths := configPaths{
+		dir:        dir,
+		userInfo:   filepath.Join(dir, userInfoFile),
+		seatConfig: filepath.Join(dir, seatConfigFile),
+		seatMap:    filepath.Join(dir, seatMapFile),
+		history:    historyPath(state, dir),
+	}
+	if userInfo != "" {
+		if paths.userInfo, err = filepath.Abs(userInfo); err != nil {
+			return configPaths{}, err
+		}
+	}
+	if seatMap != "" {
+		if paths.seatMap, err = filepath.Abs(seatMap); err != nil {
+			return configPaths{}, err
+		}
+	}
+	return paths, nil
+}
+
+// stateDir is where data the program writes for itself goes: $XDG_STATE_HOME/seat-killer,
+// usually ~/.local/state/seat-killer.
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "seat-killer"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "seat-killer"), nil
+}
+
+// historyPath returns where the run history of the config directory dir is kept
+// under state. Every config directory, usually one per account, gets its own
+// subdirectory, named after the directory and a hash of its absolute path, e.g.
+// histories/seat-killer-3f2a9c1b04d7/history.jsonl.
+func historyPath(state, dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(state, "histories", filepath.Base(dir)+"-"+hex.EncodeToString(sum[:6]), historyFile)
+}
+
+// migrateHistory moves a run history left in the config directory by earlier
+// versions to its place in the state directory, appending it when both exist.
+// The old file is removed afterwards, so this happens once per config directory.
+// It reports whether there was anything to move.
+func migrateHistory(paths configPaths) (bool, error) {
+	legacy := filepath.Join(paths.dir, historyFile)
+	if legacy == paths.history {
+		return false, nil
+	}
+	data, err := os.ReadFile(legacy)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(paths.history), 0o700); err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(paths.history, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return false, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+	return true, os.Remove(legacy)
+}
+
+// cli is what every subcommand gets: the resolved paths and where to print.
+type cli struct {
+	paths configPaths
+	// wd is the working directory the command was started in, for paths given to command flags.
+	wd             string
+	stdout, stderr io.Writer
+}
+
+// abs resolves a path given on the command line against the original working directory.
+func (c *cli) abs(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.wd, path)
+}
+
+// subcommand is one entry of the command table. run returns the exit code.
+type subcommand struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) int
+	// createsDir makes the command create a missing config directory.
+	createsDir bool
+	// aliases are other names the command answers to.
+	aliases []string
+}
+
+// subcommands lists the commands in the order they are shown in the usage text.
+// It is filled in by init to break the reference cycle through usage.
+var subcommands []subcommand
+
+func init() {
+	subcommands = []subcommand{
+		{name: "init", summary: "create the config files interactively", run: (*cli).initConfig, createsDir: true},
+		{name: "run", summary: "run today's booking task (the default)", run: func(c *cli, args []string) int { return c.booking("run", args, false) }},
+		{name: "daemon", summary: "keep running and start every scheduled booking automatically", run: func(c *cli, args []string) int { return c.booking("daemon", args, true) }},
+		{name: "plan", summary: "show what the next runs will do, with warnings", run: (*cli).plan},
+		{name: "test", summary: "check the credentials and the booking API with an invalid seat", run: (*cli).fastTest},
+		{name: "map", summary: "generate or show the seat map (map generate | map show [room])", run: (*cli).seatMap},
+		{name: "reservations", summary: "list upcoming bookings recorded in the local run history (the library is not queried); alias: bookings", run: (*cli).reservations, aliases: []string{"bookings"}},
+		{name: "history", summary: "list past runs and their outcomes", run: (*cli).history},
+		{name: "serve", summary: "serve a web UI and REST API to edit the plan and view results", run: (*cli).serve},
+	}
+}
+
+// runCLI parses the global flags and dispatches to the subcommand. It returns the exit code.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("seat-killer", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configDir := fs.String("config-dir", "", "directory holding "+seatConfigFile+" and "+userInfoFile+" (default: the current directory if it has "+seatConfigFile+", else $XDG_CONFIG_HOME/seat-killer)")
+	userInfo := fs.String("user-info", "", "path of "+userInfoFile+" (default: <config-dir>/"+userInfoFile+")")
+	seatMap := fs.String("seat-map", "", "path of the seat map (default: <config-dir>/"+seatMapFile+")")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: seat-killer [global flags] [command] [command flags]\n\nCommands:\n")
+		for _, cmd := range subcommands {
+			fmt.Fprintf(stderr, "  %-13s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintf(stderr, "\nGlobal flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	name, rest := "run", fs.Args()
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	var cmd *subcommand
+	for i := range subcommands {
+		if subcommands[i].name == name || slices.Contains(subcommands[i].aliases, name) {
+			cmd = &subcommands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		fs.Usage()
+		return 2
+	}
+
+	paths, err := resolvePaths(*configDir, *userInfo, *seatMap)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if moved, err := migrateHistory(paths); err != nil {
+		fmt.Fprintf(stderr, "cannot move the run history to %s: %v\n", paths.history, err)
+	} else if moved {
+		fmt.Fprintf(stderr, "Moved the run history to %s\n", paths.history)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if cmd.createsDir {
+		if err := os.MkdirAll(paths.dir, 0o700); err != nil {
+			fmt.Fprintf(stderr, "cannot create config directory: %v\n", err)
+			return 1
+		}
+	}
+	// Relative paths inside the configs are relative to the config directory, so
+	// that cron and systemd can start the binary from anywhere.
+	if err := os.Chdir(paths.dir); err != nil {
+		fmt.Fprintf(stderr, "cannot use config directory %s (create it or pass -config-dir): %v\n", paths.dir, err)
+		return 1
+	}
+	return cmd.run(&cli{paths: paths, wd: wd, stdout: stdout, stderr: stderr}, rest)
+}
+
+// booking implements "run" and "daemon".
+func (c *cli) booking(name string, args []string, daemon bool) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	var opts runOptions
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "go through the whole run, logging every booking request instead of sending it")
+	fs.BoolVar(&opts.mock, "mock", false, "with -dry-run, send booking requests to a local mock server")
+	fs.IntVar(&opts.mockTaken, "mock-taken", 0, "with -mock, number of the day's seats, in order, the mock reports as taken")
+	fs.StringVar(&opts.record, "record", "", "with -dry-run, append every booking request to this JSON lines file")
+	tui := fs.Bool("tui", false, "show a live dashboard of the booking window instead of scrolling logs (falls back to logs when stdout is not a terminal)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (opts.mock || opts.record != "") && !opts.dryRun {
+		fmt.Fprintln(c.stderr, "-mock and -record require -dry-run")
+		return 2
+	}
+	if opts.mockTaken < 0 || (opts.mockTaken > 0 && !opts.mock) {
+		fmt.Fprintln(c.stderr, "-mock-taken must be a non-negative count and requires -mock")
+		return 2
+	}
+	opts.record = c.abs(opts.record)
+	slog.Info("Starting Seat Killer", "config_dir", c.paths.dir)
+
+	// Ctrl-C / SIGTERM cancel every in-flight request and wait gracefully.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *tui {
+		if isTerminal(os.Stdout) {
+			opts.board = dashboard.New(c.stdout)
+			opts.board.Start()
+			slog.SetDefault(slog.New(slog.NewTextHandler(redact.NewWriter(opts.board, redact.Default), redact.Default.HandlerOptions(nil))))
+		} else {
+			slog.Warn("stdout is not a terminal, logging instead of showing the dashboard")
+		}
+	}
+
+	status := &runStatus{}
+	var err error
+	if daemon {
+		err = runDaemon(ctx, status, c.paths, opts)
+	} else {
+		_, err = runAndRecord(ctx, status, c.paths, opts)
+	}
+	if opts.board != nil {
+		// Leave the final frame on screen and log the exit status below it.
+		opts.board.Close()
+		slog.SetDefault(slog.New(slog.NewTextHandler(redact.NewWriter(c.stderr, redact.Default), redact.Default.HandlerOptions(nil))))
+	}
+	switch {
+	case ctx.Err() != nil:
+		slog.Warn("Interrupted", "status", status.String())
+		return 130
+	case err != nil:
+		slog.Error("Seat Killer failed", logging.Err(err), "status", status.String())
+		return 1
+	}
+	slog.Info("Finished", "status", status.String())
+	return 0
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runAndRecord executes one run, appends its outcome to the run history and returns it.
+func runAndRecord(ctx context.Context, status *runStatus, paths configPaths, opts runOptions) (history.Entry, error) {
+	status.reset()
+	started := time.Now()
+	err := run(ctx, status, paths, opts)
+	entry := status.result(started, err)
+	status.finish(entry)
+	store := &history.Store{Path: paths.history}
+	for _, e := range append([]history.Entry{entry}, status.extras(started)...) {
+		if appendErr := store.Append(e); appendErr != nil {
+			slog.Warn("Cannot record the run in the history", "path", paths.history, logging.Err(appendErr))
+		}
+	}
+	return entry, err
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/config"
+)
+
+func TestResolvePaths(t *testing.T) {
+	work := t.TempDir()
+	xdg := t.TempDir()
+	state := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("XDG_STATE_HOME", state)
+	t.Chdir(work)
+
+	paths, err := resolvePaths("", "", "")
+	if err != nil {
+		t.Fatalf("resolvePaths 返回错误: %v", err)
+	}
+	if want := filepath.Join(xdg, "seat-killer"); paths.dir != want || paths.history != historyPath(filepath.Join(state, "seat-killer"), want) {
+		t.Errorf("当前目录没有配置时期望使用 %s, 实际为 %+v", want, paths)
+	}
+
+	if err := os.WriteFile(seatConfigFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	paths, err = resolvePaths("", "", "")
+	if err != nil {
+		t.Fatalf("resolvePaths 返回错误: %v", err)
+	}
+	if paths.dir != work || paths.seatConfig != filepath.Join(work, seatConfigFile) || paths.history != historyPath(filepath.Join(state, "seat-killer"), work) {
+		t.Errorf("当前目录有配置时期望使用当前目录, 实际为 %+v", paths)
+	}
+
+	paths, err = resolvePaths("conf", "secrets/info.yml", "")
+	if err != nil {
+		t.Fatalf("resolvePaths 返回错误: %v", err)
+	}
+	if paths.dir != filepath.Join(work, "conf") || paths.userInfo != filepath.Join(work, "secrets", "info.yml") || paths.seatMap != filepath.Join(work, "conf", seatMapFile) {
+		t.Errorf("参数指定的路径解析错误: %+v", paths)
+	}
+}
+
+func TestHistoryPathIsPerConfigDir(t *testing.T) {
+	state := t.TempDir()
+	first := historyPath(state, "/home/a/.config/seat-killer")
+	second := historyPath(state, "/home/a/accounts/seat-killer")
+	if first == second {
+		t.Fatalf("不同配置目录的运行记录不应共用一个文件: %s", first)
+	}
+	for _, path := range []string{first, second} {
+		if !strings.HasPrefix(path, filepath.Join(state, "histories", "seat-killer-")) || filepath.Base(path) != historyFile {
+			t.Errorf("运行记录应位于状态目录下以配置目录命名的子目录中, 实际为 %s", path)
+		}
+	}
+	if again := historyPath(state, "/home/a/.config/seat-killer"); again != first {
+		t.Errorf("同一配置目录的运行记录路径应保持不变, 实际为 %s 和 %s", first, again)
+	}
+}
+
+func TestMigrateHistory(t *testing.T) {
+	state := t.TempDir()
+	configPathsFor := func(dir string) configPaths {
+		return configPaths{dir: dir, history: historyPath(state, dir)}
+	}
+	first, second := configPathsFor(t.TempDir()), configPathsFor(t.TempDir())
+
+	if moved, err := migrateHistory(first); moved || err != nil {
+		t.Fatalf("没有旧记录时不应迁移, 实际为 %v, %v", moved, err)
+	}
+	for _, p := range []configPaths{first, second} {
+		if err := os.WriteFile(filepath.Join(p.dir, historyFile), []byte("{\"account\":\""+filepath.Base(p.dir)+"\"}\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if moved, err := migrateHistory(p); !moved || err != nil {
+			t.Fatalf("期望迁移旧记录, 实际为 %v, %v", moved, err)
+		}
+		if _, err := os.Stat(filepath.Join(p.dir, historyFile)); !os.IsNotExist(err) {
+			t.Errorf("迁移后应删除旧记录, 实际为 %v", err)
+		}
+		// The old file is gone, so later runs have nothing left to move.
+		if moved, err := migrateHistory(p); moved || err != nil {
+			t.Errorf("旧记录只应迁移一次, 实际为 %v, %v", moved, err)
+		}
+	}
+	for _, p := range []configPaths{first, second} {
+		want := "{\"account\":\"" + filepath.Base(p.dir) + "\"}\n"
+		if data, _ := os.ReadFile(p.history); string(data) != want {
+			t.Errorf("每个配置目录的记录应迁移到自己的文件, 期望 %q, 实际为 %q", want, data)
+		}
+	}
+}
+
+func TestRunCLIRejectsUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"--config-dir", t.TempDir(), "bogus"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("期望退出码 2, 实际为 %d", code)
+	}
+	if !strings.Contains(stderr.String(), `unknown command "bogus"`) || !strings.Contains(stderr.String(), "reservations") {
+		t.Errorf("期望提示未知命令并列出命令:\n%s", stderr.String())
+	}
+}
+
+func TestRunCLIReservationsAlias(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Chdir(t.TempDir()) // runCLI changes into the config directory; restore it afterwards
+	dir := t.TempDir()
+	for _, name := range []string{"reservations", "bookings"} {
+		var stdout, stderr bytes.Buffer
+		if code := runCLI([]string{"--config-dir", dir, name}, &stdout, &stderr); code != 0 {
+			t.Fatalf("%s 期望退出码 0, 实际为 %d: %s", name, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "No upcoming bookings") {
+			t.Errorf("%s 期望列出尚未结束的预约, 实际为:\n%s", name, stdout.String())
+		}
+	}
+}
+
+func TestTestBeginTime(t *testing.T) {
+	open, closing := config.ClockTime(8*60), config.ClockTime(22*60)
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+	testCases := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"开放时间内", day.Add(10 * time.Hour), day.Add(11 * time.Hour)},
+		{"临近闭馆", day.Add(21*time.Hour + 30*time.Minute), day.AddDate(0, 0, 1).Add(8 * time.Hour)},
+		{"开馆之前", day.Add(6 * time.Hour), day.AddDate(0, 0, 1).Add(8 * time.Hour)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := testBeginTime(tc.now, open, closing); !got.Equal(tc.want) {
+				t.Errorf("期望 %s, 实际为 %s", tc.want, got)
+			}
+		})
+	}
+}
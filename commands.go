@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"seat-killer/booker"
+	"seat-killer/config"
+	"seat-killer/history"
+	"seat-killer/mapper"
+	"seat-killer/redact"
+	"seat-killer/sso"
+	"seat-killer/user"
+)
+
+// invalidSeatOffset moves a real seat ID out of range, so that "test" never books a seat.
+const invalidSeatOffset = 1_000_000
+
+// fastTest implements "seat-killer test": log in, fetch the user info and send one
+// booking for a seat that does not exist, to check credentials and the booking API.
+func (c *cli) fastTest(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	room := fs.String("room", "", "room to test against (default: the "+fastTestTask+" entry, else the first enabled day's room)")
+	seat := fs.String("seat", "", "seat title to derive the invalid seat ID from (default: the room's first configured seat)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	userInfo, err := config.LoadUserInfo(c.paths.userInfo)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to load %s: %v\n", c.paths.userInfo, err)
+		return 1
+	}
+	seatCfg, err := config.LoadSeatConfig(c.paths.seatConfig)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to load %s: %v\n", c.paths.seatConfig, err)
+		return 1
+	}
+	if _, err := mapper.LoadSeatMap(c.paths.seatMap); err != nil {
+		fmt.Fprintf(c.stdout, "Seat map not loaded, a dummy seat ID will be used: %v\n", err)
+	}
+	redact.Default.SetMode(redact.Mode(seatCfg.Global.Redaction.SchoolID))
+	if task, ok := testTask(seatCfg); ok {
+		if *room == "" {
+			*room = task.Name
+		}
+		if *seat == "" && task.Name == *room && len(task.Seats) > 0 {
+			*seat = task.Seats[0]
+		}
+	}
+	if *room == "" {
+		fmt.Fprintln(c.stderr, "no room to test: configure "+fastTestTask+" or an enabled day, or pass -room")
+		return 2
+	}
+
+	// Use the same login provider and network settings as a real run.
+	provider, err := sso.NewProvider(seatCfg.Global.LoginProvider, seatCfg.Global.DebugLogin)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "invalid login_provider: %v\n", err)
+		return 1
+	}
+	sso.SetProvider(provider)
+	httpTransport, _, err := newHTTPTransport(seatCfg.Global.HTTP)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "invalid http settings: %v\n", err)
+		return 1
+	}
+	sso.SetTransport(httpTransport)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(c.stdout, "Logging in as %s ...\n", redact.String(userInfo.SchoolID))
+	client, _, err := sso.Login(ctx, userInfo.SchoolID, userInfo.Password)
+	if err != nil {
+		fmt.Fprintf(c.stdout, "Login failed: %v\n", err)
+		return 1
+	}
+	loggedInUser, err := user.GetUserInfo(ctx, client)
+	if err != nil {
+		fmt.Fprintf(c.stdout, "Login succeeded, but fetching the user info failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(c.stdout, "Login successful, UID %s\n", loggedInUser.UID)
+
+	seatID := 0 // an obviously invalid seat ID
+	if id, err := mapper.GetSeatID(*room, *seat); err == nil {
+		seatID = id + invalidSeatOffset
+	}
+	bookTime := testBeginTime(time.Now(), seatCfg.Global.OpenTime, seatCfg.Global.CloseTime)
+	fmt.Fprintf(c.stdout, "Booking invalid seat ID %d in room '%s' for %s (1h) ...\n", seatID, *room, bookTime.Format("2006-01-02 15:04"))
+	result, err := booker.BookSeat(ctx, &booker.BookingRequest{
+		Client:    client,
+		UserID:    loggedInUser.UID,
+		SeatID:    seatID,
+		BeginTime: bookTime,
+		Duration:  time.Hour,
+	})
+	if result == nil {
+		fmt.Fprintf(c.stdout, "The booking API did not answer with JSON: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(c.stdout, "Booking API answered: CODE=%v MESSAGE=%s\n", result.CODE, result.MESSAGE)
+	if result.IsSuccess() {
+		fmt.Fprintln(c.stdout, "Unexpected: the invalid seat was accepted. Check your reservations.")
+		return 1
+	}
+	fmt.Fprintln(c.stdout, "OK: credentials work and the booking API rejected the invalid seat.")
+	return 0
+}
+
+// testTask picks the task "test" takes its room from: fast_test_task if present,
+// else the first enabled day of the week.
+func testTask(seatCfg *config.SeatConfig) (config.DayConfig, bool) {
+	if task, ok := seatCfg.WeekConfig[fastTestTask]; ok {
+		return task, true
+	}
+	for offset := 0; offset < 7; offset++ {
+		weekday := time.Weekday((int(time.Monday) + offset) % 7)
+		if task, ok := seatCfg.WeekConfig[weekdayNames[weekday]]; ok && task.Enable {
+			return task, true
+		}
+	}
+	return config.DayConfig{}, false
+}
+
+// testBeginTime picks a start time for the test booking: an hour from now while
+// the library is open, else the opening time of the next day.
+func testBeginTime(now time.Time, open, closing config.ClockTime) time.Time {
+	candidate := now.Add(time.Hour)
+	if !now.Before(open.On(now)) && !candidate.After(closing.On(now)) {
+		return candidate
+	}
+	return open.On(now.AddDate(0, 0, 1))
+}
+
+// seatMap implements "seat-killer map generate" and "seat-killer map show".
+func (c *cli) seatMap(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(c.stderr, "usage: seat-killer map generate [-cache file] | map show [room]")
+		return 2
+	}
+	switch args[0] {
+	case "generate":
+		return c.generateSeatMap(args[1:])
+	case "show":
+		return c.showSeatMap(args[1:])
+	}
+	fmt.Fprintf(c.stderr, "unknown map command %q, expected generate or show\n", args[0])
+	return 2
+}
+
+func (c *cli) generateSeatMap(args []string) int {
+	fs := flag.NewFlagSet("map generate", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	cache := fs.String("cache", filepath.Join("cache", "seat_data_cache.json"), "saved JSON of the library's seat search page (relative paths: the config directory)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	data, err := os.ReadFile(*cache)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to read the seat page cache: %v\n", err)
+		return 1
+	}
+	rooms, err := mapper.ParseSeatPage(data)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	f, err := os.Create(c.paths.seatMap)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to create the seat map: %v\n", err)
+		return 1
+	}
+	if err := mapper.WriteReport(f, rooms); err != nil {
+		f.Close()
+		fmt.Fprintf(c.stderr, "failed to write the seat map: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(c.stderr, "failed to write the seat map: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(c.stdout, "Wrote %d rooms to %s\n", len(rooms), c.paths.seatMap)
+	return 0
+}
+
+func (c *cli) showSeatMap(args []string) int {
+	seats, err := mapper.LoadSeatMap(c.paths.seatMap)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	if len(args) == 0 {
+		names := make([]string, 0, len(seats))
+		for name := range seats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(c.stdout, "%s (%d seats)\n", name, len(seats[name]))
+		}
+		return 0
+	}
+	room, ok := seats[args[0]]
+	if !ok {
+		fmt.Fprintf(c.stderr, "room '%s' not found in %s\n", args[0], c.paths.seatMap)
+		return 1
+	}
+	for _, seat := range room {
+		fmt.Fprintf(c.stdout, "%s\t%d\n", seat.Title, seat.SeatID)
+	}
+	return 0
+}
+
+// history implements "seat-killer history": the latest runs, oldest first.
+func (c *cli) history(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	limit := fs.Int("n", 20, "number of runs to show, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	entries, err := (&history.Store{Path: c.paths.history}).Load()
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(c.stdout, "No runs recorded yet.")
+		return 0
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("%s  %-8s %s %s", e.Time.Format("2006-01-02 15:04"), e.Outcome, e.Account, e.Weekday)
+		if e.DryRun {
+			line += " (dry run)"
+		}
+		if e.Outcome == history.Booked {
+			line += fmt.Sprintf("  %s seat %s, %s", e.Room, e.Seat, formatSlot(e))
+		} else if e.Room != "" {
+			line += "  " + e.Room
+		}
+		if e.Detail != "" {
+			line += ": " + e.Detail
+		}
+		fmt.Fprintln(c.stdout, line)
+	}
+	return 0
+}
+
+// reservations implements "seat-killer reservations" (alias "bookings"). It lists the
+// bookings recorded in the run history; the library is not queried, so bookings
+// made elsewhere do not show up.
+func (c *cli) reservations(args []string) int {
+	fs := flag.NewFlagSet("reservations", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	entries, err := (&history.Store{Path: c.paths.history}).Load()
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	upcoming := history.Upcoming(entries, time.Now())
+	if len(upcoming) == 0 {
+		fmt.Fprintln(c.stdout, "No upcoming bookings in the run history.")
+		return 0
+	}
+	for _, e := range upcoming {
+		fmt.Fprintf(c.stdout, "%s  %s  %s seat %s (ID %d)", formatSlot(e), e.Account, e.Room, e.Seat, e.SeatID)
+		if e.BookingID != "" {
+			fmt.Fprintf(c.stdout, ", booking %s", e.BookingID)
+		}
+		fmt.Fprintln(c.stdout)
+	}
+	return 0
+}
+
+// formatSlot formats the booked slot of an entry, e.g. "2026-10-21 10:00-22:00".
+func formatSlot(e history.Entry) string {
+	return fmt.Sprintf("%s %s-%s", e.Begin.Format(time.DateOnly), e.Begin.Format("15:04"), e.End().Format("15:04"))
+}
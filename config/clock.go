@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ClockTime is a time of day with minute precision, stored as minutes since midnight.
+// In YAML it accepts either a whole hour (8) or an "HH:MM" string ("08:30").
+type ClockTime int
+
+// ParseClockTime parses an "HH:MM" string into a ClockTime.
+func ParseClockTime(s string) (ClockTime, error) {
+	hourStr, minuteStr, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("时间格式无效 %q, 应为 HH:MM", s)
+	}
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("时间格式无效 %q, 小时必须在0-24之间", s)
+	}
+	minute, err := strconv.Atoi(minuteStr)
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("时间格式无效 %q, 分钟必须在0-59之间", s)
+	}
+	if hour == 24 && minute != 0 {
+		return 0, fmt.Errorf("时间格式无效 %q, 不能晚于 24:00", s)
+	}
+	return ClockTime(hour*60 + minute), nil
+}
+
+// Hour returns the hour component.
+func (c ClockTime) Hour() int { return int(c) / 60 }
+
+// Minute returns the minute component.
+func (c ClockTime) Minute() int { return int(c) % 60 }
+
+// String formats the time as "HH:MM".
+func (c ClockTime) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour(), c.Minute())
+}
+
+// On returns the ClockTime on the given day, in the day's location.
+func (c ClockTime) On(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), 0, 0, day.Location())
+}
+
+// Add returns the ClockTime shifted by d, truncated to the minute.
+func (c ClockTime) Add(d time.Duration) ClockTime {
+	return c + ClockTime(d/time.Minute)
+}
+
+// UnmarshalYAML accepts whole hours as integers and "HH:MM" strings.
+func (c *ClockTime) UnmarshalYAML(value *yaml.Node) error {
+	if value.Tag == "!!int" {
+		hour, err := strconv.Atoi(value.Value)
+		if err != nil {
+			return err
+		}
+		if hour < 0 || hour > 24 {
+			return fmt.Errorf("时间格式无效 %d, 小时必须在0-24之间", hour)
+		}
+		*c = ClockTime(hour * 60)
+		return nil
+	}
+	parsed, err := ParseClockTime(value.Value)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// MarshalYAML always writes the "HH:MM" form.
+func (c ClockTime) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+// Duration is a booking length. In YAML it accepts either whole hours (12)
+// or a Go duration string ("3h30m").
+type Duration time.Duration
+
+// Std returns the value as a time.Duration.
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+// String formats the duration without trailing zero units, e.g. "3h30m" or "12h".
+func (d Duration) String() string {
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// UnmarshalYAML accepts whole hours as integers and Go duration strings.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	if value.Tag == "!!int" {
+		hours, err := strconv.Atoi(value.Value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(time.Duration(hours) * time.Hour)
+		return nil
+	}
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("时长格式无效 %q: %w", value.Value, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML always writes the string form.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
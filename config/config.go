@@ -1,8 +1,14 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"time"
+
+	"seat-killer/credential"
+	"seat-killer/redact"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,18 +17,54 @@ import (
 type UserInfo struct {
 	SchoolID string `yaml:"school_id"`
 	Password string `yaml:"password"`
+	// PasswordRef points to the password instead of storing it, e.g. "env:HDU_PASSWORD",
+	// "file:credentials.enc" or "cmd:pass show hdu". See package credential.
+	PasswordRef string `yaml:"password_ref"`
+}
+
+// String hides the password so that logging a UserInfo never leaks it.
+func (u UserInfo) String() string {
+	return fmt.Sprintf("{SchoolID:%s Password:****}", u.SchoolID)
+}
+
+// GoString hides the password from %#v as well.
+func (u UserInfo) GoString() string {
+	return u.String()
 }
 
 func LoadUserInfo(path string) (*UserInfo, error) {
+	userInfo, err := ReadUserInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	if userInfo.PasswordRef != "" {
+		password, err := credential.Resolve(context.Background(), userInfo.PasswordRef, userInfo.SchoolID)
+		if err != nil {
+			return nil, err
+		}
+		userInfo.Password = password
+		redact.AddSecret(password)
+	}
+	return userInfo, nil
+}
+
+// ReadUserInfo parses user_info.yml without resolving password_ref, for commands
+// that only need the school ID and must not unlock a keyring or prompt for a password.
+func ReadUserInfo(path string) (*UserInfo, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 	var userInfo UserInfo
-	err = yaml.Unmarshal(data, &userInfo)
-	if err != nil {
+	if err := yaml.Unmarshal(data, &userInfo); err != nil {
 		return nil, err
 	}
+	if userInfo.PasswordRef != "" && userInfo.Password != "" {
+		return nil, fmt.Errorf("配置校验失败->'password'和'password_ref'不能同时设置")
+	}
+	// Whatever happens later, neither value may reach a log in the clear.
+	redact.AddSecret(userInfo.Password)
+	redact.AddID(userInfo.SchoolID)
 	return &userInfo, nil
 }
 
@@ -35,11 +77,158 @@ type SeatConfig struct {
 }
 
 // GlobalConfig holds settings that apply to all tasks.
-// 全局配置，目前只有提前开始时间一个配置变量
+// 全局配置：提前开始时间，以及图书馆的开放时间和预约粒度
 type GlobalConfig struct {
 	PreemptSeconds int `yaml:"preempt_seconds"`
+	// OpenTime and CloseTime bound every booking. Defaults to 07:00-22:00.
+	OpenTime  ClockTime `yaml:"open_time"`
+	CloseTime ClockTime `yaml:"close_time"`
+	// GranularityMinutes, when set, requires book_start and duration to be multiples of it.
+	GranularityMinutes int `yaml:"granularity_minutes"`
+	// PrewarmMinutes logs in this many minutes before the preempt time. 0 logs in at the preempt time.
+	PrewarmMinutes int `yaml:"prewarm_minutes"`
+	// KeepAliveSeconds is the interval of session checks while pre-warmed. Defaults to 30.
+	KeepAliveSeconds int `yaml:"keepalive_seconds"`
+	// MaxInFlight bounds the number of concurrent booking requests. Defaults to 2.
+	MaxInFlight int `yaml:"max_in_flight"`
+	// RateLimit is the request budget shared by every account booking from this machine.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	// CircuitBreaker stops hammering a host that keeps returning error pages.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	// TimeSync aligns the booking windows to the library server's clock.
+	TimeSync TimeSyncConfig `yaml:"time_sync"`
+	// HTTP tunes the connections shared by login, time sync and booking.
+	HTTP HTTPConfig `yaml:"http"`
+	// SessionCache saves the login session so later runs can skip the CAS login.
+	SessionCache SessionCacheConfig `yaml:"session_cache"`
+	// Metrics serves Prometheus metrics while running as a daemon.
+	Metrics MetricsConfig `yaml:"metrics"`
+	// Log selects the level, format and directory of the structured log.
+	Log LogConfig `yaml:"log"`
+	// Redaction controls how school IDs are masked in logs. Passwords and cookies are always masked.
+	Redaction RedactionConfig `yaml:"redaction"`
+	// LoginProvider selects the SSO login implementation: "native" (default) or "hdulib".
+	LoginProvider string `yaml:"login_provider"`
+	// DebugLogin logs every step of the native login flow.
+	DebugLogin bool `yaml:"debug_login"`
+	// MaxRelogins bounds how often an expired session is renewed during one booking window.
+	// Defaults to 2 when unset; 0 turns re-login off.
+	MaxRelogins int `yaml:"max_relogins"`
+	// ResponseRules extend the built-in classification of server refusals.
+	ResponseRules []ResponseRule `yaml:"response_rules"`
+}
+
+// RateLimitConfig tunes the token bucket that paces booking requests.
+type RateLimitConfig struct {
+	// Rate is the starting requests per second. Defaults to 4.
+	Rate float64 `yaml:"rate"`
+	// Burst is how many requests may go out back to back. Defaults to 1.
+	Burst int `yaml:"burst"`
+	// MinRate is the floor when backing off. Defaults to 0.5.
+	MinRate float64 `yaml:"min_rate"`
+	// BackoffFactor multiplies the rate on a "too frequent" response. Defaults to 0.5.
+	BackoffFactor float64 `yaml:"backoff_factor"`
+	// RecoveryPerSecond is the rate regained per second after backing off. Defaults to 0.2.
+	RecoveryPerSecond float64 `yaml:"recovery_per_second"`
+}
+
+// CircuitBreakerConfig holds the per-host circuit breaker thresholds.
+type CircuitBreakerConfig struct {
+	Enable bool `yaml:"enable"`
+	// FailureThreshold is the number of consecutive failures that opens the breaker. Defaults to 5.
+	FailureThreshold int `yaml:"failure_threshold"`
+	// OpenSeconds is how long an open breaker rejects requests before probing. Defaults to 5.
+	OpenSeconds int `yaml:"open_seconds"`
+	// HalfOpenProbes is the number of probe requests allowed while half-open. Defaults to 1.
+	HalfOpenProbes int `yaml:"half_open_probes"`
+}
+
+// TimeSyncConfig controls the server clock offset estimation.
+type TimeSyncConfig struct {
+	Enable bool `yaml:"enable"`
+	// Samples is the number of requests used for the estimate. Defaults to 8.
+	Samples int `yaml:"samples"`
+	// URL is the endpoint whose Date header is sampled. Defaults to the library host.
+	URL string `yaml:"url"`
+}
+
+// SessionCacheConfig controls the encrypted per-account session files.
+type SessionCacheConfig struct {
+	Enable bool `yaml:"enable"`
+	// Dir holds one file per account. Defaults to "sessions".
+	Dir string `yaml:"dir"`
+}
+
+// MetricsConfig controls the metrics endpoint.
+type MetricsConfig struct {
+	// Listen is the address of the /metrics endpoint, e.g. "127.0.0.1:9464". Empty disables it.
+	Listen string `yaml:"listen"`
+}
+
+// LogConfig controls the structured logger.
+type LogConfig struct {
+	// Level is debug, info (default), warn or error.
+	Level string `yaml:"level"`
+	// Format is text (default) or json.
+	Format string `yaml:"format"`
+	// Dir receives one log file per day. Defaults to "logs"; "-" disables log files.
+	Dir string `yaml:"dir"`
+}
+
+// RedactionConfig controls log masking.
+type RedactionConfig struct {
+	// SchoolID is "partial" (default, e.g. 23****01), "full" or "off".
+	SchoolID string `yaml:"school_id"`
+}
+
+// HTTPConfig tunes the shared HTTP transport.
+type HTTPConfig struct {
+	// RequestTimeoutMS bounds each request including its body. Defaults to 5000.
+	RequestTimeoutMS int `yaml:"request_timeout_ms"`
+	// DialTimeoutMS bounds establishing a TCP connection. Defaults to 3000.
+	DialTimeoutMS int `yaml:"dial_timeout_ms"`
+	// TLSTimeoutMS bounds the TLS handshake. Defaults to 3000.
+	TLSTimeoutMS int `yaml:"tls_timeout_ms"`
+	// IdleConnSeconds is how long idle keep-alive connections are kept. Defaults to 90.
+	IdleConnSeconds int `yaml:"idle_conn_seconds"`
+	// MaxIdleConnsPerHost sizes the connection pool per host. Defaults to 8.
+	MaxIdleConnsPerHost int  `yaml:"max_idle_conns_per_host"`
+	DisableHTTP2        bool `yaml:"disable_http2"`
+	// Proxy is an optional outbound proxy URL, e.g. "http://127.0.0.1:7890" or "socks5://127.0.0.1:1080".
+	Proxy string `yaml:"proxy"`
+	// DNSCacheSeconds keeps resolved addresses this long. Defaults to 300; -1 disables the cache.
+	DNSCacheSeconds int `yaml:"dns_cache_seconds"`
+	// PreResolve lists hosts resolved before the booking window. Defaults to the SSO and library hosts.
+	PreResolve []string `yaml:"pre_resolve"`
+}
+
+// ResponseRule maps a server CODE/MESSAGE pair to a booking error kind
+// (seat_taken, too_frequent, not_open, already_booked, session_expired, quota_exceeded, blocked).
+type ResponseRule struct {
+	Kind     string   `yaml:"kind"`
+	Code     string   `yaml:"code"`
+	Contains []string `yaml:"contains"`
+}
+
+// DefaultOpenTime and DefaultCloseTime apply when open_time and close_time are not set.
+const (
+	DefaultOpenTime  = ClockTime(7 * 60)
+	DefaultCloseTime = ClockTime(22 * 60)
+)
+
+// responseKinds are the booking error kinds a response rule may map to; they mirror booker's ErrorKind values.
+var responseKinds = map[string]bool{
+	"unknown": true, "seat_taken": true, "too_frequent": true, "not_open": true,
+	"already_booked": true, "session_expired": true, "quota_exceeded": true, "blocked": true,
 }
 
+const (
+	defaultMaxRelogins = 2
+	defaultKeepAlive   = 30
+	defaultSyncSamples = 8
+	defaultMaxInFlight = 2
+)
+
 // DayConfig represents the configuration for a specific day of the week.
 // It contains a single task with a prioritized list of seats.
 type DayConfig struct {
@@ -48,34 +237,295 @@ type DayConfig struct {
 	RunAtMinute   int      `yaml:"run_at_minute"` // Temporary for testing
 	Name          string   `yaml:"name"`
 	Seats         []string `yaml:"seats"`
-	BookStartHour int      `yaml:"book_start_hour"`
-	Duration      int      `yaml:"duration"`
+	BookStartHour int      `yaml:"book_start_hour"` // Legacy whole-hour form of book_start
+	// BookStart is the start of the booked slot, e.g. "08:30". Falls back to book_start_hour.
+	BookStart ClockTime `yaml:"book_start"`
+	// Duration is the booked length, e.g. 12 (hours) or "3h30m".
+	Duration Duration `yaml:"duration"`
+	// Durations lists shorter fallback lengths, tried in order when the server rejects Duration.
+	Durations []Duration `yaml:"durations"`
+	// MinDuration, used when Durations is empty, shrinks Duration hour by hour down to this length.
+	MinDuration Duration `yaml:"min_duration"`
+}
+
+// DurationCandidates returns the acceptable booking lengths, longest first.
+// The first entry is always Duration.
+func (d *DayConfig) DurationCandidates() []time.Duration {
+	candidates := []time.Duration{d.Duration.Std()}
+	if len(d.Durations) > 0 {
+		for _, fallback := range d.Durations {
+			candidates = append(candidates, fallback.Std())
+		}
+		return candidates
+	}
+	if d.MinDuration > 0 {
+		for next := d.Duration.Std() - time.Hour; next >= d.MinDuration.Std(); next -= time.Hour {
+			candidates = append(candidates, next)
+		}
+		if last := candidates[len(candidates)-1]; last > d.MinDuration.Std() {
+			candidates = append(candidates, d.MinDuration.Std())
+		}
+	}
+	return candidates
+}
+
+// BeginTime returns the start of the booked slot on the given day.
+func (d *DayConfig) BeginTime(day time.Time) time.Time {
+	return d.BookStart.On(day)
+}
+
+// normalize fills in defaults and validates the rate limit settings.
+func (r *RateLimitConfig) normalize() error {
+	if r.Rate == 0 {
+		r.Rate = 4
+	}
+	if r.Burst == 0 {
+		r.Burst = 1
+	}
+	if r.MinRate == 0 {
+		r.MinRate = min(0.5, r.Rate)
+	}
+	if r.BackoffFactor == 0 {
+		r.BackoffFactor = 0.5
+	}
+	if r.RecoveryPerSecond == 0 {
+		r.RecoveryPerSecond = 0.2
+	}
+	switch {
+	case r.Rate < 0 || r.Burst < 0 || r.MinRate < 0 || r.RecoveryPerSecond < 0:
+		return fmt.Errorf("配置校验失败->'rate_limit'中的数值不能为负数")
+	case r.MinRate > r.Rate:
+		return fmt.Errorf("配置校验失败->'rate_limit.min_rate'(%.2f)不能大于'rate'(%.2f)", r.MinRate, r.Rate)
+	case r.BackoffFactor >= 1 || r.BackoffFactor < 0:
+		return fmt.Errorf("配置校验失败->'rate_limit.backoff_factor'(%.2f)必须在0-1之间", r.BackoffFactor)
+	}
+	return nil
 }
 
+func (h *HTTPConfig) normalize() error {
+	if h.RequestTimeoutMS < 0 || h.DialTimeoutMS < 0 || h.TLSTimeoutMS < 0 || h.IdleConnSeconds < 0 || h.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("配置校验失败->'http'中的数值不能为负数")
+	}
+	if h.DNSCacheSeconds < -1 {
+		return fmt.Errorf("配置校验失败->'http.dns_cache_seconds'(%d)无效,-1表示关闭缓存", h.DNSCacheSeconds)
+	}
+	if h.Proxy != "" {
+		proxyURL, err := url.Parse(h.Proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return fmt.Errorf("配置校验失败->'http.proxy'(%s)不是有效的URL", h.Proxy)
+		}
+	}
+	if h.RequestTimeoutMS == 0 {
+		h.RequestTimeoutMS = 5000
+	}
+	if h.DialTimeoutMS == 0 {
+		h.DialTimeoutMS = 3000
+	}
+	if h.TLSTimeoutMS == 0 {
+		h.TLSTimeoutMS = 3000
+	}
+	if h.IdleConnSeconds == 0 {
+		h.IdleConnSeconds = 90
+	}
+	if h.MaxIdleConnsPerHost == 0 {
+		h.MaxIdleConnsPerHost = 8
+	}
+	if h.DNSCacheSeconds == 0 {
+		h.DNSCacheSeconds = 300
+	}
+	if h.PreResolve == nil {
+		h.PreResolve = []string{"sso.hdu.edu.cn", "hdu.huitu.zhishulib.com"}
+	}
+	return nil
+}
+
+// explicitFields records which settings whose zero value is meaningful a user_config.yml sets.
+type explicitFields struct {
+	Global struct {
+		OpenTime    *ClockTime `yaml:"open_time"`
+		CloseTime   *ClockTime `yaml:"close_time"`
+		MaxRelogins *int       `yaml:"max_relogins"`
+	} `yaml:"global"`
+	WeekConfig map[string]struct {
+		BookStart *ClockTime `yaml:"book_start"`
+	} `yaml:"week_config"`
+}
+
+// LoadSeatConfig reads and validates user_config.yml.
 func LoadSeatConfig(path string) (*SeatConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return ParseSeatConfig(data)
+}
+
+// ParseSeatConfig validates the content of a user_config.yml and fills in the defaults.
+func ParseSeatConfig(data []byte) (*SeatConfig, error) {
 	var config SeatConfig
-	err = yaml.Unmarshal(data, &config)
+	err := yaml.Unmarshal(data, &config)
 	if err != nil {
 		return nil, err
 	}
+	// 00:00 and max_relogins: 0 are valid settings, so "unset" has to come from the file itself.
+	var explicit explicitFields
+	if err := yaml.Unmarshal(data, &explicit); err != nil {
+		return nil, err
+	}
+	if explicit.Global.OpenTime == nil {
+		config.Global.OpenTime = DefaultOpenTime
+	}
+	if explicit.Global.CloseTime == nil {
+		config.Global.CloseTime = DefaultCloseTime
+	}
+	if config.Global.PrewarmMinutes < 0 || config.Global.KeepAliveSeconds < 0 {
+		return nil, fmt.Errorf("配置校验失败->'prewarm_minutes'(%d)和'keepalive_seconds'(%d)不能为负数", config.Global.PrewarmMinutes, config.Global.KeepAliveSeconds)
+	}
+	if config.Global.KeepAliveSeconds == 0 {
+		config.Global.KeepAliveSeconds = defaultKeepAlive
+	}
+	if config.Global.TimeSync.Samples < 0 {
+		return nil, fmt.Errorf("配置校验失败->'time_sync.samples'(%d)不能为负数", config.Global.TimeSync.Samples)
+	}
+	if config.Global.TimeSync.Samples == 0 {
+		config.Global.TimeSync.Samples = defaultSyncSamples
+	}
+	if config.Global.MaxInFlight < 0 {
+		return nil, fmt.Errorf("配置校验失败->'max_in_flight'(%d)不能为负数", config.Global.MaxInFlight)
+	}
+	if config.Global.MaxInFlight == 0 {
+		config.Global.MaxInFlight = defaultMaxInFlight
+	}
+	if err := config.Global.RateLimit.normalize(); err != nil {
+		return nil, err
+	}
+	if err := config.Global.HTTP.normalize(); err != nil {
+		return nil, err
+	}
+	cb := &config.Global.CircuitBreaker
+	if cb.FailureThreshold < 0 || cb.OpenSeconds < 0 || cb.HalfOpenProbes < 0 {
+		return nil, fmt.Errorf("配置校验失败->'circuit_breaker'中的数值不能为负数")
+	}
+	if cb.FailureThreshold == 0 {
+		cb.FailureThreshold = 5
+	}
+	if cb.OpenSeconds == 0 {
+		cb.OpenSeconds = 5
+	}
+	if cb.HalfOpenProbes == 0 {
+		cb.HalfOpenProbes = 1
+	}
+	if config.Global.SessionCache.Dir == "" {
+		config.Global.SessionCache.Dir = "sessions"
+	}
+	logCfg := &config.Global.Log
+	switch logCfg.Level {
+	case "":
+		logCfg.Level = "info"
+	case "debug", "info", "warn", "error":
+	default:
+		return nil, fmt.Errorf("配置校验失败->'log.level'(%s)无效,必须是 debug、info、warn 或 error", logCfg.Level)
+	}
+	switch logCfg.Format {
+	case "":
+		logCfg.Format = "text"
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("配置校验失败->'log.format'(%s)无效,必须是 text 或 json", logCfg.Format)
+	}
+	switch logCfg.Dir {
+	case "":
+		logCfg.Dir = "logs"
+	case "-":
+		logCfg.Dir = ""
+	}
+	switch config.Global.Redaction.SchoolID {
+	case "":
+		config.Global.Redaction.SchoolID = "partial"
+	case "partial", "full", "off":
+	default:
+		return nil, fmt.Errorf("配置校验失败->'redaction.school_id'(%s)无效,必须是 partial、full 或 off", config.Global.Redaction.SchoolID)
+	}
+	switch config.Global.LoginProvider {
+	case "":
+		config.Global.LoginProvider = "native"
+	case "native", "hdulib":
+	default:
+		return nil, fmt.Errorf("配置校验失败->'login_provider'(%s)无效,必须是 native 或 hdulib", config.Global.LoginProvider)
+	}
+	for i, rule := range config.Global.ResponseRules {
+		if !responseKinds[rule.Kind] {
+			return nil, fmt.Errorf("配置校验失败->'response_rules'第%d条的'kind'(%s)无效,必须是 unknown、seat_taken、too_frequent、not_open、already_booked、session_expired、quota_exceeded 或 blocked", i+1, rule.Kind)
+		}
+		if rule.Code == "" && len(rule.Contains) == 0 {
+			return nil, fmt.Errorf("配置校验失败->'response_rules'第%d条必须设置'code'或'contains'", i+1)
+		}
+	}
+	if config.Global.MaxRelogins < 0 {
+		return nil, fmt.Errorf("配置校验失败->'max_relogins'(%d)不能为负数", config.Global.MaxRelogins)
+	}
+	if explicit.Global.MaxRelogins == nil {
+		config.Global.MaxRelogins = defaultMaxRelogins
+	}
+	open, closing := config.Global.OpenTime, config.Global.CloseTime
+	if open >= closing {
+		return nil, fmt.Errorf("配置校验失败->'open_time'(%s)必须早于'close_time'(%s)", open, closing)
+	}
+	granularity := config.Global.GranularityMinutes
+	if granularity < 0 {
+		return nil, fmt.Errorf("配置校验失败->'granularity_minutes'(%d)不能为负数", granularity)
+	}
 	for day, dayConfig := range config.WeekConfig {
+		// Errors name the key the start time came from.
+		startKey := "book_start"
+		if explicit.WeekConfig[day].BookStart == nil {
+			dayConfig.BookStart = ClockTime(dayConfig.BookStartHour * 60)
+			startKey = "BookStartHour"
+		}
 		if dayConfig.RunAtHour > 24 || dayConfig.RunAtHour < 0 {
 			return nil, fmt.Errorf("配置校验失败->%s的'Run_At_Hour'(%d)无效,必须在0-24之间'", day, dayConfig.RunAtHour)
 		}
 		if dayConfig.RunAtMinute > 60 || dayConfig.RunAtMinute < 0 {
 			return nil, fmt.Errorf("配置校验失败->%s的'Run_At_Minute'(%d)无效,必须在0-60之间'", day, dayConfig.RunAtMinute)
 		}
-		if dayConfig.BookStartHour < 7 || dayConfig.BookStartHour > 22 {
-			return nil, fmt.Errorf("配置校验失败->%s的'BookStartHour'(%d)无效,必须在7-22之间'", day, dayConfig.BookStartHour)
+		seen := make(map[string]bool, len(dayConfig.Seats))
+		for _, seat := range dayConfig.Seats {
+			if seen[seat] {
+				return nil, fmt.Errorf("配置校验失败->%s的'Seats'中座位'%s'重复'", day, seat)
+			}
+			seen[seat] = true
 		}
-		if dayConfig.BookStartHour+dayConfig.Duration > 22 {
-			return nil, fmt.Errorf("配置校验失败->%s的'Duration+BookStartHour'(%d)超出合理范围,结果必须在7-22之间'", day, dayConfig.BookStartHour+dayConfig.Duration)
+		if dayConfig.BookStart < open || dayConfig.BookStart >= closing {
+			return nil, fmt.Errorf("配置校验失败->%s的'%s'(%s)无效,必须在%s-%s之间'", day, startKey, dayConfig.BookStart, open, closing)
 		}
-
+		if dayConfig.Duration.Std() <= 0 || dayConfig.Duration.Std()%time.Minute != 0 {
+			return nil, fmt.Errorf("配置校验失败->%s的'Duration'(%s)无效,必须为正的整分钟数'", day, dayConfig.Duration)
+		}
+		if end := dayConfig.BookStart.Add(dayConfig.Duration.Std()); end > closing {
+			return nil, fmt.Errorf("配置校验失败->%s的'Duration+%s'(%s)超出合理范围,结果必须在%s-%s之间'", day, startKey, end, open, closing)
+		}
+		if granularity > 0 {
+			if int(dayConfig.BookStart)%granularity != 0 {
+				return nil, fmt.Errorf("配置校验失败->%s的'BookStart'(%s)必须是%d分钟的整数倍'", day, dayConfig.BookStart, granularity)
+			}
+			if dayConfig.Duration.Std()%(time.Duration(granularity)*time.Minute) != 0 {
+				return nil, fmt.Errorf("配置校验失败->%s的'Duration'(%s)必须是%d分钟的整数倍'", day, dayConfig.Duration, granularity)
+			}
+		}
+		previous := dayConfig.Duration
+		for _, fallback := range dayConfig.Durations {
+			if fallback <= 0 || fallback >= previous || fallback.Std()%time.Minute != 0 {
+				return nil, fmt.Errorf("配置校验失败->%s的'Durations'(%s)无效,必须为整分钟数且严格短于前一个时长(%s)'", day, fallback, previous)
+			}
+			if granularity > 0 && fallback.Std()%(time.Duration(granularity)*time.Minute) != 0 {
+				return nil, fmt.Errorf("配置校验失败->%s的'Durations'(%s)必须是%d分钟的整数倍'", day, fallback, granularity)
+			}
+			previous = fallback
+		}
+		if dayConfig.MinDuration < 0 || dayConfig.MinDuration > dayConfig.Duration || dayConfig.MinDuration.Std()%time.Minute != 0 {
+			return nil, fmt.Errorf("配置校验失败->%s的'MinDuration'(%s)无效,必须为整分钟数且不超过'Duration'(%s)'", day, dayConfig.MinDuration, dayConfig.Duration)
+		}
+		config.WeekConfig[day] = dayConfig
 	}
 	return &config, nil
 }
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 // createTempConfigFile 是一个辅助函数，用于创建一个包含指定内容的临时 YAML 配置文件。
@@ -104,6 +106,166 @@ week_config:
 			expectErr:   true,
 			errContains: "Duration+BookStartHour", // 对应你的错误信息
 		},
+		{
+			name: "分钟级开始时间和时长",
+			modifier: func(y string) string {
+				y = strings.Replace(y, "book_start_hour: 8", `book_start: "08:30"`, 1)
+				return strings.Replace(y, "duration: 10", `duration: "3h30m"`, 1)
+			},
+			expectErr: false,
+		},
+		{
+			name: "分钟级时长超出闭馆时间",
+			modifier: func(y string) string {
+				y = strings.Replace(y, "book_start_hour: 8", `book_start: "18:30"`, 1)
+				return strings.Replace(y, "duration: 10", `duration: "3h31m"`, 1)
+			},
+			expectErr:   true,
+			errContains: "Duration+book_start",
+		},
+		{
+			name: "book_start 过早时报告 book_start",
+			modifier: func(y string) string {
+				return strings.Replace(y, "book_start_hour: 8", `book_start: "06:30"`, 1)
+			},
+			expectErr:   true,
+			errContains: "'book_start'",
+		},
+		{
+			name: "整数形式的闭馆时间超出范围",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  close_time: 25", 1)
+			},
+			expectErr:   true,
+			errContains: "0-24",
+		},
+		{
+			name: "开放时间晚于闭馆时间",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  open_time: \"23:00\"", 1)
+			},
+			expectErr:   true,
+			errContains: "open_time",
+		},
+		{
+			name: "不符合预约粒度",
+			modifier: func(y string) string {
+				y = strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  granularity_minutes: 30", 1)
+				return strings.Replace(y, "book_start_hour: 8", `book_start: "08:15"`, 1)
+			},
+			expectErr:   true,
+			errContains: "BookStart",
+		},
+		{
+			name: "自定义开放时间",
+			modifier: func(y string) string {
+				y = strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  open_time: \"09:00\"", 1)
+				return y
+			},
+			expectErr:   true,
+			errContains: "BookStartHour",
+		},
+		{
+			name: "降级时长列表",
+			modifier: func(y string) string {
+				return strings.Replace(y, "duration: 10", "duration: 10\n    durations: [8, \"4h30m\"]", 1)
+			},
+			expectErr: false,
+		},
+		{
+			name: "降级时长未严格递减",
+			modifier: func(y string) string {
+				return strings.Replace(y, "duration: 10", "duration: 10\n    durations: [4, 8]", 1)
+			},
+			expectErr:   true,
+			errContains: "Durations",
+		},
+		{
+			name: "最短时长超过预约时长",
+			modifier: func(y string) string {
+				return strings.Replace(y, "duration: 10", "duration: 10\n    min_duration: 11", 1)
+			},
+			expectErr:   true,
+			errContains: "MinDuration",
+		},
+		{
+			name: "无效的时长格式",
+			modifier: func(y string) string {
+				return strings.Replace(y, "duration: 10", `duration: "abc"`, 1)
+			},
+			expectErr:   true,
+			errContains: "时长格式无效",
+		},
+		{
+			name: "无效的代理地址",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  http:\n    proxy: \"127.0.0.1:7890\"", 1)
+			},
+			expectErr:   true,
+			errContains: "http.proxy",
+		},
+		{
+			name: "未知的登录方式",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  login_provider: oauth", 1)
+			},
+			expectErr:   true,
+			errContains: "login_provider",
+		},
+		{
+			name: "重复的座位",
+			modifier: func(y string) string {
+				return strings.Replace(y, `seats: ["101", "102"]`, `seats: ["101", "102", "101"]`, 1)
+			},
+			expectErr:   true,
+			errContains: "'Seats'中座位'101'重复",
+		},
+		{
+			name: "未知的响应规则类型",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  response_rules:\n    - kind: seat_gone\n      contains: [\"没了\"]", 1)
+			},
+			expectErr:   true,
+			errContains: "'response_rules'第1条的'kind'(seat_gone)无效,必须是 unknown、seat_taken",
+		},
+		{
+			name: "响应规则缺少匹配条件",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  response_rules:\n    - kind: seat_taken\n      code: \"7\"\n    - kind: not_open", 1)
+			},
+			expectErr:   true,
+			errContains: "'response_rules'第2条必须设置'code'或'contains'",
+		},
+		{
+			name: "有效的响应规则",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  response_rules:\n    - kind: seat_taken\n      contains: [\"被抢\"]\n    - kind: unknown\n      code: \"9\"", 1)
+			},
+		},
+		{
+			name: "无效的学号遮盖方式",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  redaction:\n    school_id: half", 1)
+			},
+			expectErr:   true,
+			errContains: "redaction.school_id",
+		},
+		{
+			name: "无效的日志格式",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  log:\n    format: xml", 1)
+			},
+			expectErr:   true,
+			errContains: "log.format",
+		},
+		{
+			name: "负数的请求超时",
+			modifier: func(y string) string {
+				return strings.Replace(y, "preempt_seconds: 15", "preempt_seconds: 15\n  http:\n    request_timeout_ms: -1", 1)
+			},
+			expectErr:   true,
+			errContains: "'http'",
+		},
 	}
 
 	// 遍历并执行所有测试用例
@@ -134,3 +296,171 @@ week_config:
 		})
 	}
 }
+
+func TestLoadSeatConfigMinutePrecision(t *testing.T) {
+	filePath := createTempConfigFile(t, `
+global:
+  preempt_seconds: 15
+week_config:
+  周一:
+    启用: true
+    run_at_hour: 20
+    name: "测试自习室"
+    seats: ["101"]
+    book_start: "08:30"
+    duration: "3h30m"
+`)
+	cfg, err := LoadSeatConfig(filePath)
+	if err != nil {
+		t.Fatalf("不期望出现错误，但收到了错误: %v", err)
+	}
+	day := cfg.WeekConfig["周一"]
+	begin := day.BeginTime(time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local))
+	if begin.Hour() != 8 || begin.Minute() != 30 {
+		t.Errorf("期望开始时间为 08:30, 实际为 %s", begin.Format("15:04"))
+	}
+	if day.Duration.Std() != 3*time.Hour+30*time.Minute {
+		t.Errorf("期望时长为 3h30m, 实际为 %s", day.Duration)
+	}
+	if day.Duration.String() != "3h30m" {
+		t.Errorf("期望时长字符串为 3h30m, 实际为 %s", day.Duration.String())
+	}
+}
+
+func TestLoadSeatConfigMidnight(t *testing.T) {
+	filePath := createTempConfigFile(t, `
+global:
+  preempt_seconds: 15
+  open_time: "00:00"
+  close_time: 24
+week_config:
+  周一:
+    启用: true
+    run_at_hour: 20
+    name: "测试自习室"
+    seats: ["101"]
+    book_start_hour: 8
+    book_start: "00:00"
+    duration: 2
+`)
+	cfg, err := LoadSeatConfig(filePath)
+	if err != nil {
+		t.Fatalf("不期望出现错误，但收到了错误: %v", err)
+	}
+	if cfg.Global.OpenTime.String() != "00:00" || cfg.Global.CloseTime.String() != "24:00" {
+		t.Errorf("期望开放时间为 00:00-24:00, 实际为 %s-%s", cfg.Global.OpenTime, cfg.Global.CloseTime)
+	}
+	if start := cfg.WeekConfig["周一"].BookStart.String(); start != "00:00" {
+		t.Errorf("显式设置的 book_start 00:00 不应回退到 book_start_hour, 实际为 %s", start)
+	}
+}
+
+func TestLoadSeatConfigMaxRelogins(t *testing.T) {
+	const base = `
+global:
+  preempt_seconds: 15%s
+week_config:
+  周一:
+    启用: true
+    run_at_hour: 20
+    name: "测试自习室"
+    seats: ["101"]
+    book_start_hour: 8
+    duration: 2
+`
+	testCases := []struct {
+		name    string
+		setting string
+		want    int
+	}{
+		{"未设置时使用默认值", "", 2},
+		{"设置为 0 时关闭重新登录", "\n  max_relogins: 0", 0},
+		{"显式设置", "\n  max_relogins: 5", 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := LoadSeatConfig(createTempConfigFile(t, fmt.Sprintf(base, tc.setting)))
+			if err != nil {
+				t.Fatalf("不期望出现错误，但收到了错误: %v", err)
+			}
+			if cfg.Global.MaxRelogins != tc.want {
+				t.Errorf("期望 max_relogins 为 %d, 实际为 %d", tc.want, cfg.Global.MaxRelogins)
+			}
+		})
+	}
+}
+
+func TestDurationCandidates(t *testing.T) {
+	testCases := []struct {
+		name string
+		day  DayConfig
+		want []time.Duration
+	}{
+		{
+			name: "未配置降级",
+			day:  DayConfig{Duration: Duration(12 * time.Hour)},
+			want: []time.Duration{12 * time.Hour},
+		},
+		{
+			name: "显式降级列表",
+			day:  DayConfig{Duration: Duration(12 * time.Hour), Durations: []Duration{Duration(8 * time.Hour), Duration(4 * time.Hour)}},
+			want: []time.Duration{12 * time.Hour, 8 * time.Hour, 4 * time.Hour},
+		},
+		{
+			name: "按小时缩短至最短时长",
+			day:  DayConfig{Duration: Duration(4 * time.Hour), MinDuration: Duration(90 * time.Minute)},
+			want: []time.Duration{4 * time.Hour, 3 * time.Hour, 2 * time.Hour, 90 * time.Minute},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.day.DurationCandidates()
+			if len(got) != len(tc.want) {
+				t.Fatalf("期望 %v, 实际为 %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("期望 %v, 实际为 %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadUserInfoPasswordRef(t *testing.T) {
+	t.Setenv("SEAT_KILLER_TEST_PASSWORD", "pw-env")
+
+	testCases := []struct {
+		name        string
+		content     string
+		want        string
+		errContains string
+	}{
+		{name: "明文密码", content: "school_id: \"230001\"\npassword: \"plain\"\n", want: "plain"},
+		{name: "环境变量引用", content: "school_id: \"230001\"\npassword_ref: \"env:SEAT_KILLER_TEST_PASSWORD\"\n", want: "pw-env"},
+		{name: "同时设置", content: "school_id: \"230001\"\npassword: \"plain\"\npassword_ref: \"env:SEAT_KILLER_TEST_PASSWORD\"\n", errContains: "password_ref"},
+		{name: "无法解析的引用", content: "school_id: \"230001\"\npassword_ref: \"env:SEAT_KILLER_TEST_MISSING\"\n", errContains: "not set"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			userInfo, err := LoadUserInfo(createTempConfigFile(t, tc.content))
+			if tc.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+					t.Fatalf("期望错误信息包含 '%s', 但实际错误是: %v", tc.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("不期望出现错误，但收到了错误: %v", err)
+			}
+			if userInfo.Password != tc.want {
+				t.Errorf("期望密码 %q, 实际为 %q", tc.want, userInfo.Password)
+			}
+			for _, formatted := range []string{fmt.Sprintf("%v", userInfo), fmt.Sprintf("%+v", *userInfo), fmt.Sprintf("%#v", userInfo)} {
+				if strings.Contains(formatted, tc.want) {
+					t.Errorf("格式化输出泄露了密码: %s", formatted)
+				}
+			}
+		})
+	}
+}
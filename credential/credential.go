@@ -0,0 +1,168 @@
+// Package credential resolves password references so that passwords do not
+// have to sit in plaintext in user_info.yml.
+//
+// A reference is one of:
+//
+//	env:NAME              the environment variable NAME
+//	file:PATH#ENTRY       ENTRY of the encrypted credential file PATH (ENTRY defaults to the school ID)
+//	cmd:PROGRAM ARGS...   the first line printed by PROGRAM, e.g. "cmd:pass show hdu/sso"
+//
+// Encrypted files are unlocked with the passphrase in SEAT_KILLER_PASSPHRASE or
+// the contents of the key file named by SEAT_KILLER_KEY_FILE.
+package credential
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"seat-killer/secret"
+)
+
+// Environment variables that unlock encrypted credential files.
+const (
+	PassphraseEnv = "SEAT_KILLER_PASSPHRASE"
+	KeyFileEnv    = "SEAT_KILLER_KEY_FILE"
+)
+
+// commandTimeout bounds external credential helpers.
+const commandTimeout = 30 * time.Second
+
+// ErrNoPassphrase is returned when an encrypted file is referenced but neither unlock source is set.
+var ErrNoPassphrase = fmt.Errorf("no passphrase: set %s or %s", PassphraseEnv, KeyFileEnv)
+
+// Store resolves one kind of reference. ref is the part after the scheme prefix.
+type Store interface {
+	Lookup(ctx context.Context, ref, schoolID string) (string, error)
+}
+
+// stores maps reference schemes to their backends.
+var stores = map[string]Store{
+	"env":  envStore{},
+	"file": fileStore{},
+	"cmd":  commandStore{},
+}
+
+// Resolve returns the password a reference points to. Errors never contain the password.
+func Resolve(ctx context.Context, ref, schoolID string) (string, error) {
+	scheme, rest, ok := strings.Cut(ref, ":")
+	store, known := stores[scheme]
+	if !ok || !known {
+		return "", fmt.Errorf("unsupported password_ref %q, expected env:, file: or cmd:", ref)
+	}
+	password, err := store.Lookup(ctx, rest, schoolID)
+	if err != nil {
+		return "", fmt.Errorf("resolving password_ref %q: %w", ref, err)
+	}
+	if password == "" {
+		return "", fmt.Errorf("password_ref %q resolved to an empty password", ref)
+	}
+	return password, nil
+}
+
+type envStore struct{}
+
+func (envStore) Lookup(_ context.Context, name, _ string) (string, error) {
+	password, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return password, nil
+}
+
+type fileStore struct{}
+
+func (fileStore) Lookup(_ context.Context, ref, schoolID string) (string, error) {
+	path, entry, _ := strings.Cut(ref, "#")
+	if entry == "" {
+		entry = schoolID
+	}
+	passphrase, err := Passphrase()
+	if err != nil {
+		return "", err
+	}
+	entries, err := ReadFile(path, passphrase)
+	if err != nil {
+		return "", err
+	}
+	password, ok := entries[entry]
+	if !ok {
+		return "", fmt.Errorf("no entry %q in %s", entry, path)
+	}
+	return password, nil
+}
+
+type commandStore struct{}
+
+func (commandStore) Lookup(ctx context.Context, command, _ string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// The output may contain the password; only the exit status is reported.
+		return "", fmt.Errorf("running %s: %w", args[0], err)
+	}
+	firstLine, _, _ := bytes.Cut(out, []byte("\n"))
+	return strings.TrimRight(string(firstLine), "\r"), nil
+}
+
+// Passphrase returns the passphrase for encrypted credential files.
+func Passphrase() (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if keyFile := os.Getenv(KeyFileEnv); keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("reading key file: %w", err)
+		}
+		passphrase := strings.TrimSpace(string(key))
+		if passphrase == "" {
+			return "", fmt.Errorf("key file %s is empty", keyFile)
+		}
+		return passphrase, nil
+	}
+	return "", ErrNoPassphrase
+}
+
+// ReadFile decrypts a credential file into its entries.
+func ReadFile(path, passphrase string) (map[string]string, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := secret.Open(passphrase, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	entries := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// WriteFile encrypts entries into path, readable only by the owner.
+func WriteFile(path, passphrase string, entries map[string]string) error {
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	sealed, err := secret.Seal(passphrase, plaintext)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, sealed, 0o600)
+}
@@ -0,0 +1,66 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	credFile := filepath.Join(dir, "credentials.enc")
+	if err := WriteFile(credFile, "unlock", map[string]string{"230001": "pw-file", "backup": "pw-backup"}); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("unlock\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SEAT_KILLER_TEST_PASSWORD", "pw-env")
+
+	testCases := []struct {
+		name        string
+		ref         string
+		env         map[string]string
+		want        string
+		errContains string
+	}{
+		{name: "环境变量", ref: "env:SEAT_KILLER_TEST_PASSWORD", want: "pw-env"},
+		{name: "未设置的环境变量", ref: "env:SEAT_KILLER_TEST_MISSING", errContains: "not set"},
+		{name: "加密文件-口令", ref: "file:" + credFile, env: map[string]string{PassphraseEnv: "unlock"}, want: "pw-file"},
+		{name: "加密文件-密钥文件", ref: "file:" + credFile + "#backup", env: map[string]string{KeyFileEnv: keyFile}, want: "pw-backup"},
+		{name: "加密文件-错误口令", ref: "file:" + credFile, env: map[string]string{PassphraseEnv: "wrong"}, errContains: "cannot decrypt"},
+		{name: "加密文件-缺少口令", ref: "file:" + credFile, errContains: PassphraseEnv},
+		{name: "加密文件-不存在的条目", ref: "file:" + credFile + "#nobody", env: map[string]string{PassphraseEnv: "unlock"}, errContains: "no entry"},
+		{name: "外部命令", ref: "cmd:printf pw-cmd\\nsecond-line", want: "pw-cmd"},
+		{name: "外部命令失败", ref: "cmd:false", errContains: "running false"},
+		{name: "不支持的引用", ref: "vault:hdu", errContains: "unsupported"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(PassphraseEnv, "")
+			t.Setenv(KeyFileEnv, "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			got, err := Resolve(context.Background(), tc.ref, "230001")
+			if tc.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+					t.Fatalf("期望错误信息包含 '%s', 但实际错误是: %v", tc.errContains, err)
+				}
+				if strings.Contains(err.Error(), "pw-") {
+					t.Errorf("错误信息泄露了密码: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("不期望出现错误，但收到了错误: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("期望密码 %q, 实际为 %q", tc.want, got)
+			}
+		})
+	}
+}
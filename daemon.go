@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"seat-killer/config"
+	"seat-killer/logging"
+	"seat-killer/retry"
+)
+
+const (
+	// daemonLead is how long before a task's login time the daemon starts the run,
+	// leaving room for loading configs, credential validation and time sync.
+	daemonLead = 2 * time.Minute
+	// idleRecheck is how often the daemon looks again when no day is enabled.
+	idleRecheck = time.Hour
+)
+
+// nextRunTime returns when the run for the next enabled day after now should
+// start, looking at most one week ahead.
+func nextRunTime(seatCfg *config.SeatConfig, now time.Time) (time.Time, bool) {
+	lead := time.Duration(seatCfg.Global.PreemptSeconds)*time.Second +
+		time.Duration(seatCfg.Global.PrewarmMinutes)*time.Minute + daemonLead
+	for offset := 0; offset <= 7; offset++ {
+		day := now.AddDate(0, 0, offset)
+		dayCfg, ok := seatCfg.WeekConfig[weekdayNames[day.Weekday()]]
+		if !ok || !dayCfg.Enable || len(dayCfg.Seats) == 0 {
+			continue
+		}
+		runAt := time.Date(day.Year(), day.Month(), day.Day(), dayCfg.RunAtHour, dayCfg.RunAtMinute, 0, 0, day.Location())
+		// Starting before midnight would make run pick the previous day's task.
+		start := runAt.Add(-lead)
+		if dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()); start.Before(dayStart) {
+			start = dayStart
+		}
+		if start.After(now) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// runDaemon keeps the process alive and starts a run ahead of every scheduled
+// booking. The configs are re-read before each run, so edits take effect without
+// a restart. A failed run is logged and the daemon carries on with the next one.
+func runDaemon(ctx context.Context, status *runStatus, paths configPaths, opts runOptions) error {
+	userInfo, err := config.LoadUserInfo(paths.userInfo)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", paths.userInfo, err)
+	}
+	seatCfg, err := config.LoadSeatConfig(paths.seatConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", paths.seatConfig, err)
+	}
+	if addr := seatCfg.Global.Metrics.Listen; addr != "" {
+		if err := serveMetrics(ctx, addr); err != nil {
+			return fmt.Errorf("failed to serve metrics on %s: %w", addr, err)
+		}
+	}
+	account := accountLabel(userInfo.SchoolID)
+
+	for {
+		if reloaded, err := config.LoadSeatConfig(paths.seatConfig); err != nil {
+			slog.Error("Cannot reload the seat config, keeping the previous schedule", "path", paths.seatConfig, logging.Err(err))
+		} else {
+			seatCfg = reloaded
+		}
+		next, ok := nextRunTime(seatCfg, time.Now())
+		if !ok {
+			slog.Info("No booking day is enabled, checking again later", "recheck", idleRecheck.String())
+			status.set("idle, no booking day enabled")
+			if err := retry.Sleep(ctx, idleRecheck); err != nil {
+				return err
+			}
+			continue
+		}
+		nextRunTimestamp.Set(float64(next.Unix()), account)
+		slog.Info("Next run scheduled", "at", next.Format(time.DateTime))
+		status.set("waiting for the run at %s", next.Format(time.DateTime))
+		if err := retry.Sleep(ctx, time.Until(next)); err != nil {
+			return err
+		}
+		if _, err := runAndRecord(ctx, status, paths, opts); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Error("Run failed", logging.Err(err), "status", status.String())
+		}
+	}
+}
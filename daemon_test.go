@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"seat-killer/config"
+)
+
+func TestNextRunTime(t *testing.T) {
+	seatCfg := &config.SeatConfig{
+		Global: config.GlobalConfig{PreemptSeconds: 60, PrewarmMinutes: 2},
+		WeekConfig: map[string]config.DayConfig{
+			"周一": {Enable: true, RunAtHour: 20, RunAtMinute: 0, Seats: []string{"1"}},
+			"周三": {Enable: false, RunAtHour: 20, Seats: []string{"1"}},
+			"周四": {Enable: true, RunAtHour: 0, RunAtMinute: 1, Seats: []string{"1"}},
+		},
+	}
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+
+	testCases := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"当天尚未开始", monday.Add(12 * time.Hour), monday.Add(20*time.Hour - 5*time.Minute)},
+		{"当天已经开始", monday.Add(19*time.Hour + 56*time.Minute), monday.AddDate(0, 0, 3)},
+		{"跳过未启用的日期", monday.AddDate(0, 0, 2), monday.AddDate(0, 0, 3)},
+		{"下周", monday.AddDate(0, 0, 4), monday.AddDate(0, 0, 7).Add(20*time.Hour - 5*time.Minute)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := nextRunTime(seatCfg, tc.now)
+			if !ok || !got.Equal(tc.want) {
+				t.Errorf("期望 %s, 实际为 %s (%v)", tc.want, got, ok)
+			}
+		})
+	}
+
+	if _, ok := nextRunTime(&config.SeatConfig{}, monday); ok {
+		t.Error("没有启用的日期时不应安排运行")
+	}
+}
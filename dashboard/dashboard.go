@@ -0,0 +1,343 @@
+// Package dashboard renders a live terminal view of the booking window: the
+// countdowns to the preempt and official booking times, the current phase, the
+// latest response for every seat, and a banner with the outcome of each account.
+//
+// Every method is safe for concurrent use, and a nil *Board or *Account ignores
+// all calls, so callers do not need to check whether the dashboard is enabled.
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// refreshInterval is how often the countdowns are redrawn.
+	refreshInterval = 100 * time.Millisecond
+	// logLines is how many of the latest log lines are shown below the panels.
+	logLines = 8
+	// maxLogWidth truncates long log lines and banners, so that a frame never wraps and scrolls.
+	maxLogWidth = 160
+)
+
+// ANSI escape sequences. The dashboard is only used on terminals.
+const (
+	cursorHome  = "\x1b[H"
+	clearLine   = "\x1b[K"
+	clearToEnd  = "\x1b[J"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+	bold        = "\x1b[1m"
+	green       = "\x1b[1;32m"
+	red         = "\x1b[1;31m"
+	resetColour = "\x1b[0m"
+)
+
+// Board is the whole screen: one panel per account and the tail of the log.
+type Board struct {
+	out io.Writer
+	// Now defaults to time.Now; tests override it.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	accounts []*Account
+	logs     []string
+	partial  []byte
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New returns a board drawing to out, which should be a terminal.
+func New(out io.Writer) *Board {
+	return &Board{out: out}
+}
+
+// Start clears the screen and redraws the board until Close.
+func (b *Board) Start() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.stop, b.done = make(chan struct{}), make(chan struct{})
+	b.mu.Unlock()
+	io.WriteString(b.out, hideCursor+cursorHome+clearToEnd)
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			b.draw()
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops redrawing and leaves the final frame, with the banners, on screen.
+func (b *Board) Close() {
+	if b == nil || b.stop == nil {
+		return
+	}
+	close(b.stop)
+	<-b.done
+	b.draw()
+	io.WriteString(b.out, showCursor+"\n")
+}
+
+// Write collects log output; the latest lines are shown below the panels.
+func (b *Board) Write(p []byte) (int, error) {
+	if b == nil {
+		return len(p), nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.partial = append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(b.partial, '\n')
+		if i < 0 {
+			break
+		}
+		b.logs = append(b.logs, truncate(string(b.partial[:i]), maxLogWidth))
+		b.partial = b.partial[i+1:]
+	}
+	if len(b.logs) > logLines {
+		b.logs = append(b.logs[:0], b.logs[len(b.logs)-logLines:]...)
+	}
+	return len(p), nil
+}
+
+// Account returns the panel of an account, cleared for a new run.
+func (b *Board) Account(name string) *Account {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range b.accounts {
+		if a.name == name {
+			a.reset()
+			return a
+		}
+	}
+	a := &Account{name: name, board: b, phase: "waiting"}
+	b.accounts = append(b.accounts, a)
+	return a
+}
+
+func (b *Board) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+func (b *Board) draw() {
+	var frame bytes.Buffer
+	b.Render(&frame)
+	// Overwrite the previous frame in place instead of clearing the screen, which flickers.
+	text := strings.ReplaceAll(frame.String(), "\n", clearLine+"\n")
+	io.WriteString(b.out, cursorHome+text+clearToEnd)
+}
+
+// Render writes one frame.
+func (b *Board) Render(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	fmt.Fprintf(w, "%sseat-killer%s  %s\n", bold, resetColour, now.Format("15:04:05.000"))
+	if len(b.accounts) == 0 {
+		fmt.Fprintln(w, "\nstarting up ...")
+	}
+	for _, a := range b.accounts {
+		fmt.Fprintln(w)
+		a.render(w, now)
+	}
+	if len(b.logs) > 0 {
+		fmt.Fprintf(w, "\n%sRecent log%s\n", bold, resetColour)
+		for _, line := range b.logs {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
+
+// Account is the panel of one account's run. Its methods lock the board, since
+// the board renders all panels at once.
+type Account struct {
+	name  string
+	board *Board
+
+	room                   string
+	status, phase          string
+	preempt, official, end time.Time
+	seats                  []*seatRow
+	finished, success      bool
+	summary                string
+}
+
+// seatRow is the latest response for one seat of the priority list.
+type seatRow struct {
+	title    string
+	attempts int
+	code     string
+	message  string
+	latency  time.Duration
+	dropped  bool
+	booked   bool
+}
+
+// SeatResult is the response to one booking attempt.
+type SeatResult struct {
+	Code    string
+	Message string
+	Latency time.Duration
+	// Booked is set when the attempt booked the seat.
+	Booked bool
+}
+
+func (a *Account) reset() {
+	*a = Account{name: a.name, board: a.board, phase: "waiting"}
+}
+
+// SetStatus shows what the run is doing, e.g. "waiting until 19:57:45 to log in".
+func (a *Account) SetStatus(status string) {
+	a.update(func() { a.status = status })
+}
+
+// SetWindow shows the booking windows and the seats in priority order.
+func (a *Account) SetWindow(room string, seats []string, preempt, official, end time.Time) {
+	a.update(func() {
+		a.room, a.preempt, a.official, a.end = room, preempt, official, end
+		a.seats = a.seats[:0]
+		for _, title := range seats {
+			a.seats = append(a.seats, &seatRow{title: title})
+		}
+	})
+}
+
+// SetPhase shows the phase the run is in, e.g. "login", "attack" or "fallback".
+func (a *Account) SetPhase(phase string) {
+	a.update(func() { a.phase = phase })
+}
+
+// Seat records the response to an attempt for a seat.
+func (a *Account) Seat(title string, r SeatResult) {
+	a.update(func() {
+		row := a.seat(title)
+		row.attempts++
+		row.code, row.message, row.latency = r.Code, r.Message, r.Latency
+		row.booked = row.booked || r.Booked
+	})
+}
+
+// DropSeat marks a seat the run has given up on, e.g. because it is taken.
+func (a *Account) DropSeat(title string) {
+	a.update(func() { a.seat(title).dropped = true })
+}
+
+// Finish shows the success or failure banner of the run.
+func (a *Account) Finish(success bool, summary string) {
+	a.update(func() {
+		a.finished, a.success, a.summary = true, success, summary
+		a.phase = "done"
+	})
+}
+
+func (a *Account) update(f func()) {
+	if a == nil {
+		return
+	}
+	a.board.mu.Lock()
+	f()
+	a.board.mu.Unlock()
+}
+
+// seat returns the row of a seat, adding it when it is not in the priority list.
+func (a *Account) seat(title string) *seatRow {
+	for _, row := range a.seats {
+		if row.title == title {
+			return row
+		}
+	}
+	row := &seatRow{title: title}
+	a.seats = append(a.seats, row)
+	return row
+}
+
+func (a *Account) render(w io.Writer, now time.Time) {
+	fmt.Fprintf(w, "%sAccount %s%s", bold, a.name, resetColour)
+	if a.room != "" {
+		fmt.Fprintf(w, "  room %s", a.room)
+	}
+	fmt.Fprintf(w, "  phase %s%s%s\n", bold, a.phase, resetColour)
+	if a.status != "" {
+		fmt.Fprintf(w, "  %s\n", a.status)
+	}
+	if !a.official.IsZero() {
+		fmt.Fprintf(w, "  preempt   %s  %s\n", a.preempt.Format("15:04:05.000"), countdown(a.preempt, now))
+		fmt.Fprintf(w, "  official  %s  %s\n", a.official.Format("15:04:05.000"), countdown(a.official, now))
+		fmt.Fprintf(w, "  ends      %s  %s\n", a.end.Format("15:04:05.000"), countdown(a.end, now))
+	}
+	if len(a.seats) > 0 {
+		fmt.Fprintf(w, "\n  %-8s %8s  %-12s %9s  %s\n", "SEAT", "ATTEMPTS", "CODE", "LATENCY", "MESSAGE")
+		for _, row := range a.seats {
+			fmt.Fprintf(w, "  %-8s %8d  %-12s %9s  %s\n", row.title, row.attempts, orDash(row.code), latency(row), rowMessage(row))
+		}
+	}
+	if a.finished {
+		colour, word := red, "NOT BOOKED"
+		if a.success {
+			colour, word = green, "BOOKED"
+		}
+		banner := truncate(fmt.Sprintf("  %s: %s", word, a.summary), maxLogWidth)
+		line := strings.Repeat("=", 60)
+		fmt.Fprintf(w, "\n%s%s\n%s\n%s%s\n", colour, line, banner, line, resetColour)
+	}
+}
+
+// countdown describes how far away t is, to a tenth of a second.
+func countdown(t, now time.Time) string {
+	if d := t.Sub(now); d > 0 {
+		return "in " + d.Round(100*time.Millisecond).String()
+	}
+	return "passed"
+}
+
+func latency(row *seatRow) string {
+	if row.attempts == 0 {
+		return "-"
+	}
+	return row.latency.Round(time.Millisecond).String()
+}
+
+func rowMessage(row *seatRow) string {
+	switch {
+	case row.booked:
+		return "booked"
+	case row.dropped:
+		return "dropped: " + orDash(row.message)
+	}
+	return orDash(row.message)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// truncate shortens s to at most width runes.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,75 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderShowsCountdownsSeatsAndBanner(t *testing.T) {
+	official := time.Date(2026, 10, 18, 20, 0, 0, 0, time.Local)
+	b := New(nil)
+	b.Now = func() time.Time { return official.Add(-2500 * time.Millisecond) }
+	a := b.Account("23****01")
+	a.SetWindow("一楼", []string{"35", "36"}, official.Add(-15*time.Second), official, official.Add(15*time.Second))
+	a.SetPhase("attack")
+	a.Seat("35", SeatResult{Code: "1", Message: "该座位已被预约", Latency: 83 * time.Millisecond})
+	a.DropSeat("35")
+	a.Seat("36", SeatResult{Code: "0", Message: "预约成功", Latency: 41 * time.Millisecond, Booked: true})
+
+	var frame strings.Builder
+	b.Render(&frame)
+	for _, want := range []string{"23****01", "room 一楼", "attack", "passed", "in 2.5s", "dropped: 该座位已被预约", "83ms", "booked"} {
+		if !strings.Contains(frame.String(), want) {
+			t.Errorf("期望画面包含 %q:\n%s", want, frame.String())
+		}
+	}
+	if strings.Contains(frame.String(), "BOOKED:") {
+		t.Errorf("未结束时不应显示结果横幅:\n%s", frame.String())
+	}
+
+	a.Finish(true, "seat 36 in 一楼")
+	frame.Reset()
+	b.Render(&frame)
+	if !strings.Contains(frame.String(), "BOOKED: seat 36 in 一楼") || !strings.Contains(frame.String(), "phase \x1b[1mdone") {
+		t.Errorf("期望显示成功横幅:\n%s", frame.String())
+	}
+
+	// A new run of the same account starts from a clean panel.
+	b.Account("23****01").Finish(false, "failed: all attempts failed")
+	frame.Reset()
+	b.Render(&frame)
+	if strings.Contains(frame.String(), "seat 36") || !strings.Contains(frame.String(), "NOT BOOKED: failed") {
+		t.Errorf("期望新一轮运行清空面板并显示失败横幅:\n%s", frame.String())
+	}
+}
+
+func TestWriteKeepsTheLatestLogLines(t *testing.T) {
+	b := New(nil)
+	for i := range logLines + 3 {
+		fmt.Fprintf(b, "line %d\n", i)
+	}
+	fmt.Fprint(b, "partial")
+	if len(b.logs) != logLines || b.logs[0] != "line 3" || b.logs[logLines-1] != fmt.Sprintf("line %d", logLines+2) {
+		t.Errorf("日志尾部错误: %v", b.logs)
+	}
+	if string(b.partial) != "partial" {
+		t.Errorf("不完整的行应等待换行, 实际为 %q", b.partial)
+	}
+}
+
+func TestNilBoardIgnoresCalls(t *testing.T) {
+	var b *Board
+	b.Start()
+	a := b.Account("x")
+	a.SetStatus("status")
+	a.SetWindow("room", []string{"1"}, time.Now(), time.Now(), time.Now())
+	a.Seat("1", SeatResult{})
+	a.DropSeat("1")
+	a.Finish(true, "")
+	if n, err := b.Write([]byte("log\n")); n != 4 || err != nil {
+		t.Errorf("nil Board 的 Write 应丢弃输出, 实际为 %d, %v", n, err)
+	}
+	b.Close()
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"seat-killer/booker"
+	"seat-killer/dashboard"
+	"seat-killer/logging"
+	"seat-killer/mapper"
+	"seat-killer/mockserver"
+	"seat-killer/redact"
+)
+
+// runOptions are the command line switches that change how a run behaves.
+type runOptions struct {
+	// dryRun goes through the whole timeline but never sends a booking to the library.
+	dryRun bool
+	// mock sends the dry run's bookings to a local mock server instead of dropping them.
+	mock bool
+	// mockTaken is how many of the day's seats, in order, the mock reports as taken.
+	mockTaken int
+	// record appends every booking request of a dry run to this JSON lines file.
+	record string
+	// openAt replaces the day's configured booking time, so that a dry run can
+	// rehearse the window right away. Zero keeps the configured time.
+	openAt time.Time
+	// board is the live dashboard; nil when the run logs as usual.
+	board *dashboard.Board
+}
+
+// dryRunResponse is what a dry run answers in place of the library. Its CODE is
+// unknown to every classifier, so the engine keeps building requests each tick.
+const dryRunResponse = `{"CODE":"dry_run","MESSAGE":"dry run: request not sent"}`
+
+// recordedRequest is one line of the record file.
+type recordedRequest struct {
+	Time      time.Time         `json:"time"`
+	URL       string            `json:"url"`
+	SeatID    string            `json:"seat_id"`
+	BeginTime string            `json:"begin_time"`
+	Duration  string            `json:"duration"`
+	Form      map[string]string `json:"form"`
+	Header    map[string]string `json:"header"`
+}
+
+// dryRunTransport logs, and optionally records, every booking request. Unless
+// passThrough is set (when bookings go to the mock server) it answers them itself
+// instead of sending them. All other traffic, logins included, is untouched.
+type dryRunTransport struct {
+	base        http.RoundTripper
+	passThrough bool
+
+	mu     sync.Mutex
+	record *os.File
+}
+
+func newDryRunTransport(base http.RoundTripper, opts runOptions) (*dryRunTransport, error) {
+	t := &dryRunTransport{base: base, passThrough: opts.mock}
+	if opts.record != "" {
+		f, err := os.OpenFile(opts.record, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dry-run record file: %w", err)
+		}
+		t.record = f
+	}
+	return t, nil
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, booker.BookPath) {
+		return t.base.RoundTrip(req)
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	t.observe(req.Context(), req, body)
+	if t.passThrough {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		// The mock listens on loopback, which a configured proxy could not reach.
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(dryRunResponse)),
+		ContentLength: int64(len(dryRunResponse)),
+		Request:       req,
+	}, nil
+}
+
+// observe logs the booking request that would be sent and appends it to the record file.
+func (t *dryRunTransport) observe(ctx context.Context, req *http.Request, body []byte) {
+	form, _ := url.ParseQuery(string(body))
+	rec := recordedRequest{
+		Time:     time.Now(),
+		URL:      req.URL.String(),
+		SeatID:   form.Get("seats[0]"),
+		Duration: form.Get("duration") + "s",
+		Form:     make(map[string]string, len(form)),
+		Header:   make(map[string]string, len(req.Header)),
+	}
+	if begin, err := strconv.ParseInt(form.Get("beginTime"), 10, 64); err == nil {
+		rec.BeginTime = time.Unix(begin, 0).Format(time.DateTime)
+	}
+	for key := range form {
+		rec.Form[key] = form.Get(key)
+	}
+	for key := range req.Header {
+		if key == "Cookie" {
+			continue // the session is not part of what is being tested
+		}
+		rec.Header[key] = req.Header.Get(key)
+	}
+	logging.From(ctx).Info("Dry run: booking request",
+		"url", rec.URL,
+		"seat_id", rec.SeatID,
+		"begin", rec.BeginTime,
+		"duration", rec.Duration,
+		"form", redact.String(string(body)),
+		"sent", t.passThrough)
+
+	if t.record == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.record.Write(append(line, '\n')); err != nil {
+		logging.From(ctx).Warn("Cannot write dry-run record", logging.Err(err))
+	}
+}
+
+// Close closes the record file.
+func (t *dryRunTransport) Close() error {
+	if t.record == nil {
+		return nil
+	}
+	return t.record.Close()
+}
+
+// startMockServer serves the booking API locally for a dry run and points the
+// booker at it. Booking opens at openAt and the first taken seats of the day are
+// refused, so that the fallback phase is exercised too. The returned func stops the
+// mock and points the booker back at where it pointed before.
+func startMockServer(ctx context.Context, openAt time.Time, room string, seats []string, taken int) (func(), error) {
+	srv := mockserver.New(openAt)
+	for _, seatNum := range seats[:min(taken, len(seats))] {
+		if seatID, err := mapper.GetSeatID(room, seatNum); err == nil {
+			srv.Take(seatID)
+		}
+	}
+	previous := booker.Endpoint()
+	endpoint, err := srv.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mock server: %w", err)
+	}
+	booker.SetEndpoint(endpoint)
+	logging.From(ctx).Info("Dry run: bookings go to the mock server", "endpoint", endpoint, "opens_at", openAt.Format("15:04:05.000"), "taken", min(taken, len(seats)))
+	return func() {
+		booker.SetEndpoint(previous)
+		if err := srv.Close(); err != nil {
+			slog.Warn("Mock server did not shut down cleanly", logging.Err(err))
+		}
+	}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/booker"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestDryRunTransportInterceptsBookings(t *testing.T) {
+	recordPath := filepath.Join(t.TempDir(), "dry-run.jsonl")
+	var forwarded []string
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		forwarded = append(forwarded, req.URL.Path)
+		return nil, errors.New("base transport must not be used for bookings")
+	})
+	dryRun, err := newDryRunTransport(base, runOptions{dryRun: true, record: recordPath})
+	if err != nil {
+		t.Fatalf("newDryRunTransport 返回错误: %v", err)
+	}
+	client := &http.Client{Transport: dryRun}
+
+	begin := time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local)
+	for i := 0; i < 2; i++ {
+		_, err = booker.BookSeat(context.Background(), &booker.BookingRequest{
+			Client: client, UserID: "42", SeatID: 101, BeginTime: begin, Duration: 2 * time.Hour,
+		})
+		var refusal *booker.BookingError
+		if !errors.As(err, &refusal) || refusal.Kind != booker.KindUnknown {
+			t.Fatalf("期望未知类型的拒绝以便继续尝试, 实际为 %v", err)
+		}
+	}
+	if _, err := client.Get("https://hdu.huitu.zhishulib.com/"); err == nil {
+		t.Fatal("期望其他请求交给底层 transport")
+	}
+	if len(forwarded) != 1 || forwarded[0] != "/" {
+		t.Fatalf("期望只转发非预约请求, 实际为 %v", forwarded)
+	}
+	if err := dryRun.Close(); err != nil {
+		t.Fatalf("关闭记录文件失败: %v", err)
+	}
+
+	data, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatalf("读取记录文件失败: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("期望记录 2 个请求, 实际为 %d", len(lines))
+	}
+	var rec recordedRequest
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("记录不是合法 JSON: %v", err)
+	}
+	if rec.SeatID != "101" || rec.Duration != "7200s" || rec.BeginTime != begin.Format(time.DateTime) || rec.Form["seatBookers[0]"] != "42" {
+		t.Errorf("记录内容错误: %+v", rec)
+	}
+	if rec.Header["Api-Token"] == "" {
+		t.Errorf("期望记录 api-token 请求头: %+v", rec.Header)
+	}
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"time"
+)
+
+// Result tells the engine what an attempt achieved and how to proceed.
+type Result struct {
+	// Success ends the run; outstanding attempts are cancelled.
+	Success bool
+	// Drop removes the target from rotation for the rest of the run.
+	Drop bool
+	// Stop aborts the whole run with this error.
+	Stop error
+	// Backoff pauses all dispatching for this long and restarts from the highest priority.
+	Backoff time.Duration
+}
+
+// Attempt performs one booking attempt for target. It must honour ctx cancellation.
+type Attempt func(ctx context.Context, target string) Result
+
+// Report describes how a run ended.
+type Report struct {
+	// Winner is the target whose attempt succeeded, empty otherwise.
+	Winner string
+	// Err is the Stop error of the attempt that aborted the run, if any.
+	Err error
+	// Attempts counts dispatched attempts.
+	Attempts int
+}
+
+// Limiter paces dispatches. *ratelimit.Limiter satisfies it.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Engine dispatches attempts concurrently: up to MaxInFlight requests are
+// outstanding at any time, and every dispatch first takes a permit from Limiter.
+// Targets are dispatched in rounds; every round walks the targets in priority
+// order, so a slow response for a high-priority target never delays the
+// dispatch of lower-priority ones, and a new round always starts from the top.
+type Engine struct {
+	MaxInFlight int
+	// Limiter may be shared between engines to enforce a global budget. Nil means unpaced.
+	Limiter Limiter
+}
+
+type completion struct {
+	target string
+	result Result
+}
+
+// Run dispatches attempts until one succeeds, one asks to stop, every target
+// is dropped, or ctx is done. It returns only after all outstanding attempts
+// have finished, which they do promptly as their context is cancelled.
+func (e *Engine) Run(ctx context.Context, targets []string, attempt Attempt) Report {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	maxInFlight := max(e.MaxInFlight, 1)
+	done := make(chan completion, maxInFlight)
+	inFlight := make(map[string]bool)
+	dropped := make(map[string]bool)
+	var report Report
+	cursor := 0
+	pauseUntil := time.Now()
+
+	// permits yields one value per token taken from the limiter.
+	permits := make(chan struct{})
+	go func() {
+		for {
+			if e.Limiter != nil && e.Limiter.Wait(ctx) != nil {
+				return
+			}
+			select {
+			case permits <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	finish := func() Report {
+		cancel()
+		for len(inFlight) > 0 {
+			c := <-done
+			delete(inFlight, c.target)
+			if c.result.Success && report.Winner == "" && report.Err == nil {
+				report.Winner = c.target
+			}
+		}
+		return report
+	}
+
+	handle := func(c completion) bool {
+		delete(inFlight, c.target)
+		switch {
+		case c.result.Success:
+			report.Winner = c.target
+			return true
+		case c.result.Stop != nil:
+			report.Err = c.result.Stop
+			return true
+		case c.result.Drop:
+			dropped[c.target] = true
+		}
+		if c.result.Backoff > 0 {
+			pauseUntil = time.Now().Add(c.result.Backoff)
+			cursor = 0
+		}
+		return false
+	}
+
+	for {
+		if len(dropped) == len(targets) {
+			return finish()
+		}
+
+		// Pick the next target of the current round, skipping dropped ones and
+		// those still waiting for a response. Walking past the end starts a new round.
+		pick := -1
+		if len(inFlight) < maxInFlight {
+			for i := 0; i < len(targets); i++ {
+				idx := (cursor + i) % len(targets)
+				if t := targets[idx]; !dropped[t] && !inFlight[t] {
+					pick = idx
+					break
+				}
+			}
+		}
+
+		var dispatch <-chan struct{}
+		var pause <-chan time.Time
+		var timer *time.Timer
+		if pick >= 0 {
+			if wait := time.Until(pauseUntil); wait > 0 {
+				timer = time.NewTimer(wait)
+				pause = timer.C
+			} else {
+				dispatch = permits
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return finish()
+		case c := <-done:
+			stopTimer(timer)
+			if handle(c) {
+				return finish()
+			}
+		case <-pause:
+		case <-dispatch:
+			target := targets[pick]
+			cursor = (pick + 1) % len(targets)
+			inFlight[target] = true
+			report.Attempts++
+			go func() {
+				done <- completion{target: target, result: attempt(ctx, target)}
+			}()
+		}
+	}
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// spacer lets one dispatch through every interval.
+type spacer struct{ interval time.Duration }
+
+func (s spacer) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.interval):
+		return nil
+	}
+}
+
+func TestRunDispatchesInPriorityOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	e := &Engine{MaxInFlight: 3, Limiter: spacer{5 * time.Millisecond}}
+
+	report := e.Run(context.Background(), []string{"a", "b", "c"}, func(ctx context.Context, target string) Result {
+		mu.Lock()
+		order = append(order, target)
+		n := len(order)
+		mu.Unlock()
+		return Result{Success: n == 5}
+	})
+
+	if report.Winner == "" {
+		t.Fatalf("期望成功, 实际报告为 %+v", report)
+	}
+	want := []string{"a", "b", "c", "a", "b"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("期望派发顺序 %v, 实际为 %v", want, order)
+		}
+	}
+}
+
+func TestRunCancelsOutstandingOnSuccess(t *testing.T) {
+	var cancelled atomic.Int32
+	e := &Engine{MaxInFlight: 2, Limiter: spacer{time.Millisecond}}
+
+	start := time.Now()
+	report := e.Run(context.Background(), []string{"slow", "fast"}, func(ctx context.Context, target string) Result {
+		if target == "fast" {
+			return Result{Success: true}
+		}
+		<-ctx.Done()
+		cancelled.Add(1)
+		return Result{}
+	})
+
+	if report.Winner != "fast" {
+		t.Fatalf("期望 fast 成功, 实际为 %+v", report)
+	}
+	if cancelled.Load() != 1 {
+		t.Errorf("期望慢请求被取消")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("慢请求阻塞了引擎")
+	}
+}
+
+func TestRunRespectsMaxInFlight(t *testing.T) {
+	var current, peak atomic.Int32
+	e := &Engine{MaxInFlight: 2}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	e.Run(ctx, []string{"a", "b", "c", "d"}, func(ctx context.Context, target string) Result {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		current.Add(-1)
+		return Result{}
+	})
+
+	if peak.Load() > 2 {
+		t.Errorf("期望并发数不超过 2, 实际峰值为 %d", peak.Load())
+	}
+}
+
+func TestRunStopsAndDrops(t *testing.T) {
+	e := &Engine{MaxInFlight: 1}
+
+	report := e.Run(context.Background(), []string{"a", "b"}, func(ctx context.Context, target string) Result {
+		return Result{Drop: true}
+	})
+	if report.Winner != "" || report.Err != nil || report.Attempts != 2 {
+		t.Errorf("期望所有目标被丢弃后结束, 实际为 %+v", report)
+	}
+
+	stop := errors.New("already booked")
+	report = e.Run(context.Background(), []string{"a", "b"}, func(ctx context.Context, target string) Result {
+		return Result{Stop: stop}
+	})
+	if !errors.Is(report.Err, stop) {
+		t.Errorf("期望返回停止原因, 实际为 %+v", report)
+	}
+}
@@ -0,0 +1,114 @@
+// Package history keeps a local record of every run, so that users can look up
+// what happened on a given night and which reservations the tool has made.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Outcome is how a run ended.
+type Outcome string
+
+const (
+	Booked  Outcome = "booked"  // a seat was booked
+	Failed  Outcome = "failed"  // every attempt within the windows was refused
+	Stopped Outcome = "stopped" // retrying was pointless, e.g. the account already holds a booking
+	Skipped Outcome = "skipped" // nothing to do: the day is disabled or its window had passed
+	Error   Outcome = "error"   // the run failed or was interrupted before it could finish
+)
+
+// Entry is one run.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Account string    `json:"account"` // redacted school ID
+	Weekday string    `json:"weekday,omitempty"`
+	Room    string    `json:"room,omitempty"`
+	Outcome Outcome   `json:"outcome"`
+	DryRun  bool      `json:"dry_run,omitempty"`
+	// Seat, SeatID, BookingID, Begin and Duration describe the booked slot.
+	Seat      string        `json:"seat,omitempty"`
+	SeatID    int           `json:"seat_id,omitempty"`
+	BookingID string        `json:"booking_id,omitempty"`
+	Phase     string        `json:"phase,omitempty"`
+	Begin     time.Time     `json:"begin,omitzero"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	// Detail is a human-readable summary, e.g. the error that ended the run.
+	Detail string `json:"detail,omitempty"`
+}
+
+// End returns when the booked slot ends.
+func (e Entry) End() time.Time {
+	return e.Begin.Add(e.Duration)
+}
+
+// Store is a JSON lines file of entries, oldest first.
+type Store struct {
+	Path string
+}
+
+// Append adds an entry to the end of the store, creating the file if needed.
+func (s *Store) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return f.Close()
+}
+
+// Load returns all entries. A missing file is an empty history.
+func (s *Store) Load() ([]Entry, error) {
+	f, err := os.Open(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid history entry: %w", s.Path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// Upcoming returns the real (not dry-run) bookings whose slot has not ended at now.
+func Upcoming(entries []Entry, now time.Time) []Entry {
+	var upcoming []Entry
+	for _, e := range entries {
+		if e.Outcome == Booked && !e.DryRun && e.End().After(now) {
+			upcoming = append(upcoming, e)
+		}
+	}
+	return upcoming
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	store := &Store{Path: filepath.Join(t.TempDir(), "state", "history.jsonl")}
+	if entries, err := store.Load(); err != nil || len(entries) != 0 {
+		t.Fatalf("文件不存在时期望空历史, 实际为 %v, %v", entries, err)
+	}
+
+	now := time.Date(2026, 10, 19, 20, 0, 5, 0, time.Local)
+	booked := Entry{
+		Time: now, Account: "23****01", Weekday: "周一", Room: "一楼", Outcome: Booked,
+		Seat: "35", SeatID: 101, BookingID: "b1", Phase: "attack",
+		Begin: now.AddDate(0, 0, 2).Add(-10 * time.Hour), Duration: 12 * time.Hour,
+	}
+	skipped := Entry{Time: now.AddDate(0, 0, 1), Account: "23****01", Weekday: "周二", Outcome: Skipped, Detail: "disabled"}
+	for _, e := range []Entry{booked, skipped} {
+		if err := store.Append(e); err != nil {
+			t.Fatalf("Append 返回错误: %v", err)
+		}
+	}
+
+	entries, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load 返回错误: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("期望 2 条记录, 实际为 %d", len(entries))
+	}
+	if got := entries[0]; got.BookingID != "b1" || !got.Begin.Equal(booked.Begin) || got.Duration != booked.Duration {
+		t.Errorf("记录内容不一致: %+v", got)
+	}
+	if !entries[1].Begin.IsZero() {
+		t.Errorf("未预约的记录不应有开始时间: %+v", entries[1])
+	}
+
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		t.Fatalf("Stat 返回错误: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("期望文件权限 0600, 实际为 %o", perm)
+	}
+}
+
+func TestLoadRejectsCorruptLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := os.WriteFile(path, []byte(`{"outcome":"booked"}`+"\nnot json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := (&Store{Path: path}).Load()
+	if err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Fatalf("期望报告第 2 行错误, 实际为 %v", err)
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	entries := []Entry{
+		{Outcome: Booked, Seat: "ended", Begin: now.Add(-5 * time.Hour), Duration: 4 * time.Hour},
+		{Outcome: Booked, Seat: "ongoing", Begin: now.Add(-time.Hour), Duration: 4 * time.Hour},
+		{Outcome: Booked, Seat: "future", Begin: now.Add(48 * time.Hour), Duration: time.Hour},
+		{Outcome: Booked, Seat: "dry", DryRun: true, Begin: now.Add(48 * time.Hour), Duration: time.Hour},
+		{Outcome: Failed, Seat: "failed", Begin: now.Add(48 * time.Hour), Duration: time.Hour},
+	}
+	upcoming := Upcoming(entries, now)
+	if len(upcoming) != 2 || upcoming[0].Seat != "ongoing" || upcoming[1].Seat != "future" {
+		t.Fatalf("期望 ongoing 与 future, 实际为 %+v", upcoming)
+	}
+}
@@ -0,0 +1,569 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"seat-killer/config"
+	"seat-killer/credential"
+	"seat-killer/mapper"
+	"seat-killer/sso"
+)
+
+// Defaults the wizard proposes; they match the sample user_config.yml.
+const (
+	defaultRunAt      = config.ClockTime(20 * 60)
+	defaultBookStart  = config.ClockTime(10 * 60)
+	defaultPreempt    = 15
+	defaultPrewarm    = 3
+	maxPasswordChecks = 3
+)
+
+// weekdayOrder lists the week_config keys from Monday to Sunday.
+var weekdayOrder = []string{"周一", "周二", "周三", "周四", "周五", "周六", "周日"}
+
+// wizardAnswers is everything the wizard collects.
+type wizardAnswers struct {
+	schoolID    string
+	password    string // empty when passwordRef is used
+	passwordRef string
+	room        string
+	seats       []string
+	days        map[string]bool
+	runAt       config.ClockTime
+	bookStart   config.ClockTime
+	duration    time.Duration
+	preempt     int
+	prewarm     int
+}
+
+// wizard asks the questions of "seat-killer init". Its dependencies are fields so
+// that tests can script the answers.
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	// readPassword reads a line without echoing it when possible.
+	readPassword func() (string, error)
+	// validate logs in once to check the credentials.
+	validate func(ctx context.Context, schoolID, password string) error
+	rooms    mapper.SeatMapper
+	// open and closing bound the booked slot, as in config.LoadSeatConfig.
+	open, closing config.ClockTime
+}
+
+// ask prints a prompt and returns the trimmed answer, or def when it is empty.
+func (w *wizard) ask(prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", prompt)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("no answer to %q: %w", prompt, err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askUntil repeats a question until parse accepts the answer.
+func askUntil[T any](w *wizard, prompt, def string, parse func(string) (T, error)) (T, error) {
+	for {
+		answer, err := w.ask(prompt, def)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		value, err := parse(answer)
+		if err == nil {
+			return value, nil
+		}
+		fmt.Fprintf(w.out, "  %v\n", err)
+	}
+}
+
+// confirm asks a yes/no question.
+func (w *wizard) confirm(prompt string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	return askUntil(w, prompt+" ("+hint+")", "", func(answer string) (bool, error) {
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes", "是":
+			return true, nil
+		case "n", "no", "否":
+			return false, nil
+		}
+		return false, errors.New("please answer y or n")
+	})
+}
+
+// run asks all questions.
+func (w *wizard) run(ctx context.Context) (*wizardAnswers, error) {
+	a := &wizardAnswers{}
+	if err := w.askCredentials(ctx, a); err != nil {
+		return nil, err
+	}
+	if err := w.askSeats(a); err != nil {
+		return nil, err
+	}
+	if err := w.askSchedule(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (w *wizard) askCredentials(ctx context.Context, a *wizardAnswers) error {
+	fmt.Fprintln(w.out, "\n== Account ==")
+	var err error
+	if a.schoolID, err = askUntil(w, "School ID", "", nonEmpty("school ID")); err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		fmt.Fprint(w.out, "Password (leave empty to use a password_ref such as env:HDU_PASSWORD): ")
+		password, err := w.readPassword()
+		if err != nil {
+			return fmt.Errorf("no password: %w", err)
+		}
+		a.password, a.passwordRef = password, ""
+		if password == "" {
+			if a.passwordRef, err = askUntil(w, "password_ref", "", nonEmpty("password_ref")); err != nil {
+				return err
+			}
+			if password, err = credential.Resolve(ctx, a.passwordRef, a.schoolID); err != nil {
+				fmt.Fprintf(w.out, "  %v\n", err)
+				continue
+			}
+		}
+
+		fmt.Fprintln(w.out, "Checking the credentials with the SSO ...")
+		err = w.validate(ctx, a.schoolID, password)
+		if err == nil {
+			fmt.Fprintln(w.out, "  Login successful.")
+			return nil
+		}
+		fmt.Fprintf(w.out, "  Login failed: %v\n", err)
+		if errors.Is(err, sso.ErrBadCredentials) && attempt < maxPasswordChecks {
+			continue
+		}
+		keep, confirmErr := w.confirm("Save these credentials anyway?", false)
+		if confirmErr != nil {
+			return confirmErr
+		}
+		if keep {
+			return nil
+		}
+		if attempt >= maxPasswordChecks {
+			return errors.New("credentials could not be validated")
+		}
+	}
+}
+
+func (w *wizard) askSeats(a *wizardAnswers) error {
+	fmt.Fprintln(w.out, "\n== Room and seats ==")
+	names := make([]string, 0, len(w.rooms))
+	for name := range w.rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		fmt.Fprintf(w.out, "  %2d. %s (%s)\n", i+1, name, seatRange(w.rooms[name]))
+	}
+	var err error
+	a.room, err = askUntil(w, "Room (number or name)", "", func(answer string) (string, error) {
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(names) {
+			return names[n-1], nil
+		}
+		if _, ok := w.rooms[answer]; ok {
+			return answer, nil
+		}
+		return "", fmt.Errorf("unknown room %q", answer)
+	})
+	if err != nil {
+		return err
+	}
+
+	seats := w.rooms[a.room]
+	for {
+		a.seats, err = askUntil(w, "Seats in priority order, e.g. \"35 36 37\"", "", func(answer string) ([]string, error) {
+			return parseSeatList(answer, seats)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w.out, "  Preview:")
+		for i, title := range a.seats {
+			fmt.Fprintf(w.out, "    %d. seat %s -> ID %d%s\n", i+1, title, seatID(seats, title), neighbours(seats, title))
+		}
+		ok, err := w.confirm("Use these seats?", true)
+		if err != nil || ok {
+			return err
+		}
+	}
+}
+
+func (w *wizard) askSchedule(a *wizardAnswers) error {
+	fmt.Fprintln(w.out, "\n== Weekly schedule ==")
+	fmt.Fprintln(w.out, "Each run books the day after tomorrow; e.g. the Monday run books Wednesday.")
+	var err error
+	if a.days, err = askUntil(w, "Days to run (1=周一 ... 7=周日, e.g. 1-5,7)", "1-7", parseDays); err != nil {
+		return err
+	}
+	if a.runAt, err = askUntil(w, "Booking opens at", defaultRunAt.String(), config.ParseClockTime); err != nil {
+		return err
+	}
+	a.bookStart, err = askUntil(w, "Booked slot starts at", defaultBookStart.String(), func(answer string) (config.ClockTime, error) {
+		start, err := config.ParseClockTime(answer)
+		if err == nil && (start < w.open || start >= w.closing) {
+			err = fmt.Errorf("must be between %s and %s", w.open, w.closing)
+		}
+		return start, err
+	})
+	if err != nil {
+		return err
+	}
+	longest := time.Duration(w.closing-a.bookStart) * time.Minute
+	a.duration, err = askUntil(w, "Booked length, e.g. 4h or 3h30m", config.Duration(longest).String(), func(answer string) (time.Duration, error) {
+		d, err := time.ParseDuration(answer)
+		if err != nil {
+			return 0, err
+		}
+		if d <= 0 || d > longest || d%time.Minute != 0 {
+			return 0, fmt.Errorf("must be whole minutes, at most %s", config.Duration(longest))
+		}
+		return d, nil
+	})
+	if err != nil {
+		return err
+	}
+	if a.preempt, err = askUntil(w, "Seconds to start before the opening time", strconv.Itoa(defaultPreempt), nonNegative); err != nil {
+		return err
+	}
+	a.prewarm, err = askUntil(w, "Minutes to log in before that", strconv.Itoa(defaultPrewarm), nonNegative)
+	return err
+}
+
+func nonEmpty(what string) func(string) (string, error) {
+	return func(answer string) (string, error) {
+		if answer == "" {
+			return "", fmt.Errorf("the %s is required", what)
+		}
+		return answer, nil
+	}
+}
+
+func nonNegative(answer string) (int, error) {
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 0 {
+		return 0, errors.New("must be a whole number, 0 or more")
+	}
+	return n, nil
+}
+
+// parseDays parses a day selection such as "1-5,7" into week_config keys.
+func parseDays(answer string) (map[string]bool, error) {
+	days := make(map[string]bool)
+	for _, part := range strings.Split(answer, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			to = from
+		}
+		first, err1 := strconv.Atoi(strings.TrimSpace(from))
+		last, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || first < 1 || last > 7 || first > last {
+			return nil, fmt.Errorf("invalid day selection %q, use numbers 1-7", part)
+		}
+		for day := first; day <= last; day++ {
+			days[weekdayOrder[day-1]] = true
+		}
+	}
+	return days, nil
+}
+
+// parseSeatList splits a list of seat titles and checks that each exists in the room.
+func parseSeatList(answer string, seats []mapper.SeatInfo) ([]string, error) {
+	titles := strings.FieldsFunc(answer, func(r rune) bool { return r == ' ' || r == ',' || r == '，' })
+	if len(titles) == 0 {
+		return nil, errors.New("enter at least one seat")
+	}
+	seen := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		if seatID(seats, title) == 0 {
+			return nil, fmt.Errorf("seat %q is not in this room (%s)", title, seatRange(seats))
+		}
+		if seen[title] {
+			return nil, fmt.Errorf("seat %q is listed twice", title)
+		}
+		seen[title] = true
+	}
+	return titles, nil
+}
+
+func seatID(seats []mapper.SeatInfo, title string) int {
+	for _, seat := range seats {
+		if seat.Title == title {
+			return seat.SeatID
+		}
+	}
+	return 0
+}
+
+// seatRange summarises the seat titles of a room, e.g. "1-424" or "12 seats".
+func seatRange(seats []mapper.SeatInfo) string {
+	lowest, highest := 0, 0
+	for i, seat := range seats {
+		n, err := strconv.Atoi(seat.Title)
+		if err != nil {
+			return fmt.Sprintf("%d seats", len(seats))
+		}
+		if i == 0 || n < lowest {
+			lowest = n
+		}
+		if i == 0 || n > highest {
+			highest = n
+		}
+	}
+	return fmt.Sprintf("%d-%d", lowest, highest)
+}
+
+// neighbours names the seats listed next to title in the seat map, which are
+// usually physically next to it, as a hint for picking fallbacks.
+func neighbours(seats []mapper.SeatInfo, title string) string {
+	for i, seat := range seats {
+		if seat.Title != title {
+			continue
+		}
+		var near []string
+		if i > 0 {
+			near = append(near, seats[i-1].Title)
+		}
+		if i+1 < len(seats) {
+			near = append(near, seats[i+1].Title)
+		}
+		if len(near) > 0 {
+			return " (next to " + strings.Join(near, ", ") + ")"
+		}
+	}
+	return ""
+}
+
+// renderUserInfo formats user_info.yml.
+func renderUserInfo(a *wizardAnswers) string {
+	var b strings.Builder
+	b.WriteString("# Generated by seat-killer init. Keep this file private.\n")
+	fmt.Fprintf(&b, "school_id: %s\n", strconv.Quote(a.schoolID))
+	if a.passwordRef != "" {
+		fmt.Fprintf(&b, "password_ref: %s\n", strconv.Quote(a.passwordRef))
+	} else {
+		fmt.Fprintf(&b, "password: %s\n", strconv.Quote(a.password))
+	}
+	return b.String()
+}
+
+// renderSeatConfig formats user_config.yml with one entry per weekday; days that
+// were not selected are written disabled, so enabling one later is a one-line edit.
+func renderSeatConfig(a *wizardAnswers) string {
+	quoted := make([]string, len(a.seats))
+	for i, title := range a.seats {
+		quoted[i] = strconv.Quote(title)
+	}
+	var b strings.Builder
+	b.WriteString("# Generated by seat-killer init; run \"seat-killer plan\" to check the schedule.\n\n")
+	b.WriteString("# 全局抢座参数\nglobal:\n")
+	fmt.Fprintf(&b, "  preempt_seconds: %d  # 提前 %d 秒开始抢座\n", a.preempt, a.preempt)
+	fmt.Fprintf(&b, "  prewarm_minutes: %d  # 提前 %d 分钟登录预热，0 表示到点再登录\n", a.prewarm, a.prewarm)
+	b.WriteString("  session_cache:\n    enable: true       # 加密保存登录会话，下次运行时优先复用\n\n")
+	b.WriteString("# 每日抢座计划\nweek_config:\n")
+	for i, day := range weekdayOrder {
+		target := weekdayOrder[(i+2)%7]
+		if i >= 5 {
+			target = "下" + target
+		}
+		fmt.Fprintf(&b, "  %s: # 预约目标：%s\n", day, target)
+		fmt.Fprintf(&b, "    启用: %t\n", a.days[day])
+		fmt.Fprintf(&b, "    run_at_hour: %d\n", a.runAt.Hour())
+		fmt.Fprintf(&b, "    run_at_minute: %d\n", a.runAt.Minute())
+		fmt.Fprintf(&b, "    name: %s\n", strconv.Quote(a.room))
+		fmt.Fprintf(&b, "    seats: [%s]\n", strings.Join(quoted, ", "))
+		fmt.Fprintf(&b, "    book_start: %q\n", a.bookStart.String())
+		fmt.Fprintf(&b, "    duration: %q\n", config.Duration(a.duration).String())
+		if i < len(weekdayOrder)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// writeValidated writes content to path through a temporary file that must pass
+// check first, so a failed init never leaves a broken config behind. An existing
+// file keeps its permissions; a new one is only readable by the owner.
+func writeValidated(path, content string, check func(path string) error) error {
+	tmp, err := stageValidated(path, content, check)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Rename(tmp, path)
+}
+
+// stageValidated writes content to a temporary file next to path and runs check on
+// it. The caller renames the returned file into place or removes it.
+func stageValidated(path, content string, check func(path string) error) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	name := tmp.Name()
+	if info, err := os.Stat(path); err == nil {
+		if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+			tmp.Close()
+			os.Remove(name)
+			return "", err
+		}
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		os.Remove(name)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	if err := check(name); err != nil {
+		os.Remove(name)
+		return "", fmt.Errorf("%s is invalid: %w", filepath.Base(path), err)
+	}
+	return name, nil
+}
+
+// readPasswordFromTerminal reads a line from in, turning off the terminal echo
+// with stty while doing so when stdin is a terminal.
+func readPasswordFromTerminal(in *bufio.Reader, out io.Writer) func() (string, error) {
+	return func() (string, error) {
+		if isTerminal(os.Stdin) {
+			if stty("-echo") == nil {
+				defer func() {
+					stty("echo")
+					fmt.Fprintln(out)
+				}()
+			}
+		}
+		line, err := in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+}
+
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// initConfig implements "seat-killer init": an interactive wizard that writes
+// user_info.yml and user_config.yml into the config directory.
+func (c *cli) initConfig(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	force := fs.Bool("force", false, "overwrite existing files without asking")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	rooms, err := mapper.LoadSeatMap(c.paths.seatMap)
+	if err != nil {
+		// A fresh config directory has no seat map yet; copy the one from the directory init was started in.
+		if data, readErr := os.ReadFile(filepath.Join(c.wd, seatMapFile)); readErr == nil && os.WriteFile(c.paths.seatMap, data, 0o644) == nil {
+			fmt.Fprintf(c.stdout, "Copied %s to %s.\n", filepath.Join(c.wd, seatMapFile), c.paths.seatMap)
+			rooms, err = mapper.LoadSeatMap(c.paths.seatMap)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "%v\nThe wizard needs the seat map to offer rooms; pass -seat-map or run \"seat-killer map generate\" first.\n", err)
+		return 1
+	}
+	in := bufio.NewReader(os.Stdin)
+	w := &wizard{
+		in:           in,
+		out:          c.stdout,
+		readPassword: readPasswordFromTerminal(in, c.stdout),
+		validate:     sso.ValidateCredentials,
+		rooms:        rooms,
+		open:         config.DefaultOpenTime,
+		closing:      config.DefaultCloseTime,
+	}
+	fmt.Fprintf(c.stdout, "This wizard writes %s and %s.\n", c.paths.userInfo, c.paths.seatConfig)
+	if !*force {
+		for _, path := range []string{c.paths.userInfo, c.paths.seatConfig} {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			overwrite, err := w.confirm(path+" exists. Overwrite it?", false)
+			if err != nil {
+				fmt.Fprintln(c.stderr, err)
+				return 1
+			}
+			if !overwrite {
+				fmt.Fprintln(c.stdout, "Nothing written.")
+				return 0
+			}
+		}
+	}
+
+	answers, err := w.run(context.Background())
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	if err := c.writeConfigs(answers); err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	fmt.Fprintf(c.stdout, "\nWrote %s and %s. Run \"seat-killer plan\" to see the schedule.\n", c.paths.userInfo, c.paths.seatConfig)
+	return 0
+}
+
+// writeConfigs writes both files, and only once both pass the loaders' validation.
+func (c *cli) writeConfigs(a *wizardAnswers) error {
+	seatTmp, err := stageValidated(c.paths.seatConfig, renderSeatConfig(a), func(path string) error {
+		_, err := config.LoadSeatConfig(path)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(seatTmp)
+	infoTmp, err := stageValidated(c.paths.userInfo, renderUserInfo(a), func(path string) error {
+		_, err := config.LoadUserInfo(path)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(infoTmp)
+	if err := os.Rename(seatTmp, c.paths.seatConfig); err != nil {
+		return err
+	}
+	if err := os.Rename(infoTmp, c.paths.userInfo); err != nil {
+		return err
+	}
+	return os.Chmod(c.paths.userInfo, 0o600)
+}
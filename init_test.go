@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/config"
+	"seat-killer/mapper"
+	"seat-killer/sso"
+)
+
+func TestWizardWritesValidConfigs(t *testing.T) {
+	answers := strings.Join([]string{
+		"230001", // school ID
+		"wrong",  // password, rejected by the SSO
+		"secret", // password
+		"9",      // room number out of range
+		"1",      // 一楼
+		"35 99",  // seat 99 does not exist
+		"36, 35", // seats
+		"n",      // reject the preview
+		"35 36",  // seats again
+		"",       // accept the preview
+		"1-5",    // days
+		"",       // default opening time
+		"08:30",  // slot start
+		"14h",    // too long
+		"4h",     // duration
+		"",       // default preempt
+		"0",      // prewarm
+	}, "\n") + "\n"
+	in := bufio.NewReader(strings.NewReader(answers))
+	var checked []string
+	w := &wizard{
+		in:  in,
+		out: io.Discard,
+		readPassword: func() (string, error) {
+			line, err := in.ReadString('\n')
+			return strings.TrimSpace(line), err
+		},
+		validate: func(_ context.Context, schoolID, password string) error {
+			checked = append(checked, password)
+			if password != "secret" {
+				return sso.ErrBadCredentials
+			}
+			return nil
+		},
+		rooms: mapper.SeatMapper{
+			"一楼": {{SeatID: 101, Title: "35"}, {SeatID: 102, Title: "36"}},
+			"二楼": {{SeatID: 201, Title: "1"}},
+		},
+		open:    config.DefaultOpenTime,
+		closing: config.DefaultCloseTime,
+	}
+
+	a, err := w.run(context.Background())
+	if err != nil {
+		t.Fatalf("wizard 返回错误: %v", err)
+	}
+	if len(checked) != 2 {
+		t.Errorf("期望校验两次密码, 实际为 %v", checked)
+	}
+
+	dir := t.TempDir()
+	c := &cli{paths: configPaths{
+		dir:        dir,
+		userInfo:   filepath.Join(dir, userInfoFile),
+		seatConfig: filepath.Join(dir, seatConfigFile),
+	}}
+	if err := c.writeConfigs(a); err != nil {
+		t.Fatalf("writeConfigs 返回错误: %v", err)
+	}
+
+	userInfo, err := config.LoadUserInfo(c.paths.userInfo)
+	if err != nil || userInfo.SchoolID != "230001" || userInfo.Password != "secret" {
+		t.Fatalf("user_info.yml 内容错误: %v, %v", userInfo, err)
+	}
+	seatCfg, err := config.LoadSeatConfig(c.paths.seatConfig)
+	if err != nil {
+		t.Fatalf("生成的 user_config.yml 未通过校验: %v", err)
+	}
+	if seatCfg.Global.PreemptSeconds != defaultPreempt || seatCfg.Global.PrewarmMinutes != 0 {
+		t.Errorf("全局参数错误: %+v", seatCfg.Global)
+	}
+	monday, friday, saturday := seatCfg.WeekConfig["周一"], seatCfg.WeekConfig["周五"], seatCfg.WeekConfig["周六"]
+	if !monday.Enable || !friday.Enable || saturday.Enable {
+		t.Errorf("启用的日期错误: 周一=%t 周五=%t 周六=%t", monday.Enable, friday.Enable, saturday.Enable)
+	}
+	if monday.Name != "一楼" || strings.Join(monday.Seats, ",") != "35,36" {
+		t.Errorf("房间或座位错误: %s %v", monday.Name, monday.Seats)
+	}
+	if monday.RunAtHour != 20 || monday.RunAtMinute != 0 || monday.BookStart.String() != "08:30" || monday.Duration.Std() != 4*time.Hour {
+		t.Errorf("时间设置错误: %+v", monday)
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	days, err := parseDays("1-3, 7")
+	if err != nil {
+		t.Fatalf("parseDays 返回错误: %v", err)
+	}
+	if len(days) != 4 || !days["周一"] || !days["周三"] || !days["周日"] || days["周四"] {
+		t.Errorf("解析结果错误: %v", days)
+	}
+	for _, bad := range []string{"0", "8", "5-3", "周一"} {
+		if _, err := parseDays(bad); err == nil {
+			t.Errorf("期望 %q 报错", bad)
+		}
+	}
+}
+
+func TestWriteConfigsLeavesBothFilesWhenOneIsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	c := &cli{paths: configPaths{
+		dir:        dir,
+		userInfo:   filepath.Join(dir, userInfoFile),
+		seatConfig: filepath.Join(dir, seatConfigFile),
+	}}
+	const oldSeatConfig = "# existing config\n"
+	if err := os.WriteFile(c.paths.seatConfig, []byte(oldSeatConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := &wizardAnswers{
+		schoolID:    "230001",
+		passwordRef: "env:SEAT_KILLER_TEST_UNSET_PASSWORD", // cannot be resolved
+		room:        "一楼",
+		seats:       []string{"35"},
+		days:        map[string]bool{"周一": true},
+		runAt:       config.ClockTime(20 * 60),
+		bookStart:   config.ClockTime(8 * 60),
+		duration:    4 * time.Hour,
+		preempt:     defaultPreempt,
+	}
+	if err := c.writeConfigs(a); err == nil || !strings.Contains(err.Error(), userInfoFile) {
+		t.Fatalf("期望 user_info.yml 校验失败, 实际为 %v", err)
+	}
+	if data, _ := os.ReadFile(c.paths.seatConfig); string(data) != oldSeatConfig {
+		t.Errorf("另一个文件校验失败时不应替换 user_config.yml:\n%s", data)
+	}
+	if _, err := os.Stat(c.paths.userInfo); !os.IsNotExist(err) {
+		t.Errorf("校验失败时不应写入 user_info.yml: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("不应留下临时文件: %v", entries)
+	}
+}
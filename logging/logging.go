@@ -0,0 +1,165 @@
+// Package logging sets up the process-wide structured logger and defines the
+// field names shared by every package, so log aggregation can rely on them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"seat-killer/redact"
+)
+
+// Field keys used across packages.
+const (
+	KeyAccount = "account"
+	KeyRoom    = "room"
+	KeySeat    = "seat"
+	KeyPhase   = "phase"
+	KeyAttempt = "attempt"
+	KeyLatency = "latency_ms"
+	KeyCode    = "code"
+	KeyError   = "err"
+)
+
+// Account, Room, Seat and friends build the shared fields.
+func Account(schoolID string) slog.Attr { return slog.String(KeyAccount, schoolID) }
+func Room(name string) slog.Attr        { return slog.String(KeyRoom, name) }
+func Seat(seat string) slog.Attr        { return slog.String(KeySeat, seat) }
+func Phase(name string) slog.Attr       { return slog.String(KeyPhase, name) }
+func Attempt(n int) slog.Attr           { return slog.Int(KeyAttempt, n) }
+func Code(code any) slog.Attr           { return slog.String(KeyCode, fmt.Sprint(code)) }
+
+// Latency records d in milliseconds.
+func Latency(d time.Duration) slog.Attr { return slog.Int64(KeyLatency, d.Milliseconds()) }
+
+// Err records an error; a nil error yields an empty attribute, which slog drops.
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.String(KeyError, err.Error())
+}
+
+// Config selects the level, output format and log directory.
+type Config struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string
+	// Format is "text" or "json".
+	Format string
+	// Dir receives one file per day, e.g. logs/seat-killer-2026-10-18.log. Empty disables files.
+	Dir string
+}
+
+// ParseLevel converts a level name to a slog.Level.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// New builds a logger writing to stderr and, if configured, to the daily file.
+// Everything it writes is redacted. The returned closer closes the log file.
+func New(cfg Config, stderr io.Writer) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := stderr
+	var closer io.Closer = noFile{}
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+			return nil, nil, err
+		}
+		file := &DailyFile{Dir: cfg.Dir, Prefix: "seat-killer"}
+		out = io.MultiWriter(stderr, file)
+		closer = file
+	}
+	out = redact.NewWriter(out, redact.Default)
+
+	opts := redact.Default.HandlerOptions(level)
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, opts)
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	return slog.New(handler), closer, nil
+}
+
+type noFile struct{}
+
+func (noFile) Close() error { return nil }
+
+// DailyFile appends to <Dir>/<Prefix>-YYYY-MM-DD.log and switches files when the date changes.
+type DailyFile struct {
+	Dir    string
+	Prefix string
+	// Now defaults to time.Now; tests override it.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	date string
+	file *os.File
+}
+
+// Write implements io.Writer.
+func (f *DailyFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now
+	if f.Now != nil {
+		now = f.Now
+	}
+	date := now().Format("2006-01-02")
+	if f.file == nil || date != f.date {
+		if f.file != nil {
+			f.file.Close()
+		}
+		file, err := os.OpenFile(filepath.Join(f.Dir, f.Prefix+"-"+date+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			f.file = nil
+			return 0, err
+		}
+		f.file, f.date = file, date
+	}
+	return f.file.Write(p)
+}
+
+// Close closes the current file.
+func (f *DailyFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+type ctxKey struct{}
+
+// With returns a context whose logger carries attrs, e.g. the account of a run.
+func With(ctx context.Context, attrs ...any) context.Context {
+	return context.WithValue(ctx, ctxKey{}, From(ctx).With(attrs...))
+}
+
+// From returns the logger stored by With, or slog.Default.
+func From(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/redact"
+)
+
+func TestJSONFieldsAndRedaction(t *testing.T) {
+	redact.AddSecret("hunter2")
+	var buf bytes.Buffer
+	logger, closer, err := New(Config{Level: "info", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	logger.Debug("hidden")
+	logger.Info("booking refused", Account("230001"), Room("二楼西"), Seat("101"), Attempt(2), Latency(1500*time.Millisecond), Code(1), Err(errors.New("password=hunter2")))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("期望只有一条 info 日志, 实际为:\n%s", buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("日志不是合法的 JSON: %v", err)
+	}
+	want := map[string]any{"seat": "101", "room": "二楼西", "attempt": 2.0, "latency_ms": 1500.0, "code": "1"}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("字段 %s 期望 %v, 实际为 %v", k, v, entry[k])
+		}
+	}
+	if strings.Contains(lines[0], "hunter2") {
+		t.Errorf("日志泄露了密码: %s", lines[0])
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	testCases := []Config{{Level: "verbose", Format: "text"}, {Level: "info", Format: "xml"}}
+	for _, cfg := range testCases {
+		if _, _, err := New(cfg, &bytes.Buffer{}); err == nil {
+			t.Errorf("期望配置 %+v 返回错误", cfg)
+		}
+	}
+}
+
+func TestDailyFileRotates(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 18, 23, 59, 0, 0, time.Local)
+	file := &DailyFile{Dir: dir, Prefix: "seat-killer", Now: func() time.Time { return now }}
+	defer file.Close()
+
+	file.Write([]byte("first\n"))
+	now = now.Add(2 * time.Minute)
+	file.Write([]byte("second\n"))
+
+	for name, want := range map[string]string{"seat-killer-2026-10-18.log": "first\n", "seat-killer-2026-10-19.log": "second\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s 期望内容 %q, 实际为 %q (%v)", name, want, got, err)
+		}
+	}
+}
+
+func TestRedactsSecretsTheHandlerEscapes(t *testing.T) {
+	const password = `pa"ss\word`
+	redact.AddSecret(password)
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, closer, err := New(Config{Level: "info", Format: format}, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closer.Close()
+
+			logger.Info("login with "+password, "form", "username=a&pwd="+password, Err(errors.New("rejected "+password)), "detail", errors.New(password))
+
+			out := buf.String()
+			for _, leaked := range []string{password, `pa\"ss\\word`, `ss\word`, `ss\\word`} {
+				if strings.Contains(out, leaked) {
+					t.Errorf("日志泄露了密码 (%s):\n%s", leaked, out)
+				}
+			}
+			if strings.Count(out, "****") != 4 {
+				t.Errorf("期望消息和三个字段中的密码都被遮盖:\n%s", out)
+			}
+		})
+	}
+}
@@ -1,227 +1,549 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"seat-killer/booker"
+	"seat-killer/breaker"
 	"seat-killer/config"
+	"seat-killer/dashboard"
+	"seat-killer/history"
+	"seat-killer/logging"
 	"seat-killer/mapper"
+	"seat-killer/ratelimit"
+	"seat-killer/redact"
 	"seat-killer/retry"
 	"seat-killer/sso"
+	"seat-killer/timesync"
+	"seat-killer/transport"
 	"seat-killer/user"
 )
 
 const (
 	// Total duration of the fallback window after the official booking time.
 	fallbackWindow = 15 * time.Second
-	// Interval between requests.
-	requestInterval = 500 * time.Millisecond
+	// warmURL is requested to open connections to the library host before booking.
+	warmURL = "https://hdu.huitu.zhishulib.com/"
 )
 
+// weekdayNames maps weekdays to the keys of week_config.
+var weekdayNames = map[time.Weekday]string{
+	time.Sunday: "周日", time.Monday: "周一", time.Tuesday: "周二",
+	time.Wednesday: "周三", time.Thursday: "周四", time.Friday: "周五",
+	time.Saturday: "周六",
+}
+
 func main() {
-	log.Println("Starting Seat Killer...")
+	// Until the configured logger is installed, log as text to stderr; secrets are
+	// masked on the way out either way.
+	slog.SetDefault(slog.New(slog.NewTextHandler(redact.NewWriter(os.Stderr, redact.Default), redact.Default.HandlerOptions(nil))))
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// runStatus records how far a run got, so an interrupted run can still report it,
+// and what it achieved, for the run history.
+type runStatus struct {
+	mu    sync.Mutex
+	stage string
+	entry history.Entry
+	// extra holds the bookings the run made besides the one in entry.
+	extra []history.Entry
+	// view is the dashboard panel of the run, if any; it mirrors the stage.
+	view *dashboard.Account
+}
+
+// show attaches the dashboard panel of the current run.
+func (s *runStatus) show(view *dashboard.Account) {
+	s.mu.Lock()
+	s.view = view
+	s.mu.Unlock()
+}
+
+// finish shows the outcome of a run on its dashboard panel.
+func (s *runStatus) finish(e history.Entry) {
+	s.mu.Lock()
+	view := s.view
+	s.mu.Unlock()
+	switch e.Outcome {
+	case history.Booked:
+		view.Finish(true, fmt.Sprintf("seat %s in %s, %s", e.Seat, e.Room, formatSlot(e)))
+	case history.Skipped:
+		// Nothing was attempted; the status line says why.
+	default:
+		view.Finish(false, fmt.Sprintf("%s: %s", e.Outcome, e.Detail))
+	}
+}
+
+// record updates the history entry of the current run.
+func (s *runStatus) record(update func(e *history.Entry)) {
+	s.mu.Lock()
+	update(&s.entry)
+	s.mu.Unlock()
+}
 
+// reset starts a new run.
+func (s *runStatus) reset() {
+	s.mu.Lock()
+	s.stage, s.entry, s.extra, s.view = "", history.Entry{}, nil, nil
+	s.mu.Unlock()
+}
+
+// result returns the history entry of a run that started at started and ended with err.
+func (s *runStatus) result(started time.Time, err error) history.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry
+	e.Time = started
+	if err != nil {
+		e.Outcome = history.Error
+		e.Detail = fmt.Sprintf("%v (while %s)", err, s.stage)
+	} else if e.Outcome == "" {
+		e.Outcome = history.Error
+		e.Detail = "run ended without an outcome while " + s.stage
+	}
+	return e
+}
+
+// also records a booking the run made besides its main one.
+func (s *runStatus) also(e history.Entry) {
+	s.mu.Lock()
+	s.extra = append(s.extra, e)
+	s.mu.Unlock()
+}
+
+// extras returns the history entries of the bookings recorded with also, for a run that started at started.
+func (s *runStatus) extras(started time.Time) []history.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]history.Entry, 0, len(s.extra))
+	for _, e := range s.extra {
+		e.Time, e.Account, e.Weekday, e.Room, e.DryRun = started, s.entry.Account, s.entry.Weekday, s.entry.Room, s.entry.DryRun
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (s *runStatus) set(format string, args ...any) {
+	s.mu.Lock()
+	s.stage = fmt.Sprintf(format, args...)
+	s.view.SetStatus(s.stage)
+	s.mu.Unlock()
+}
+
+func (s *runStatus) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stage == "" {
+		return "starting up"
+	}
+	return s.stage
+}
+
+// processGlobals are the process-wide settings a run replaces with its own.
+type processGlobals struct {
+	logger    *slog.Logger
+	provider  sso.LoginProvider
+	transport http.RoundTripper
+	redaction redact.Mode
+}
+
+func currentGlobals() processGlobals {
+	return processGlobals{
+		logger:    slog.Default(),
+		provider:  sso.Provider(),
+		transport: sso.Transport(),
+		redaction: redact.Default.Mode(),
+	}
+}
+
+// restore puts the settings back, e.g. after a dry run inside the web server.
+func (g processGlobals) restore() {
+	slog.SetDefault(g.logger)
+	sso.SetProvider(g.provider)
+	sso.SetTransport(g.transport)
+	redact.Default.SetMode(g.redaction)
+}
+
+// run executes today's booking task. Every wait and request is bound to ctx, and
+// network work is additionally bounded by the booking window it belongs to.
+// The logger, login provider, transport and redaction mode the run installs are
+// only in place while it runs, so that a long-lived caller keeps its own.
+func run(ctx context.Context, status *runStatus, paths configPaths, opts runOptions) error {
+	previous := currentGlobals()
+	defer previous.restore()
 	// --- 1. Load Configs & Map ---
+	status.set("loading configuration")
 	//通过 user_info 加载当前用户信息结构体
-	userInfo, err := config.LoadUserInfo("user_info.yml")
+	userInfo, err := config.LoadUserInfo(paths.userInfo)
 	if err != nil {
-		log.Fatalf("Failed to load user_info.yml: %v", err)
+		return fmt.Errorf("failed to load %s: %w", paths.userInfo, err)
 	}
 	// 通过 user_config 读取抢座任务信息，并返回 go 语言可读取的结构体
-	seatCfg, err := config.LoadSeatConfig("user_config.yml")
+	seatCfg, err := config.LoadSeatConfig(paths.seatConfig)
 	if err != nil {
-		log.Fatalf("Failed to load user_config.yml: %v", err)
+		return fmt.Errorf("failed to load %s: %w", paths.seatConfig, err)
 	}
+	status.record(func(e *history.Entry) {
+		e.Account = accountLabel(userInfo.SchoolID)
+		e.DryRun = opts.dryRun
+	})
+	view := opts.board.Account(accountLabel(userInfo.SchoolID))
+	status.show(view)
 
-	if _, err = mapper.LoadSeatMap("seat_report.txt"); err != nil {
-		log.Fatalf("Failed to load seat map: %v", err)
+	if _, err = mapper.LoadSeatMap(paths.seatMap); err != nil {
+		return fmt.Errorf("failed to load seat map: %w", err)
+	}
+	// Attributes are redacted when they are attached to a logger, so the mode
+	// has to be in place before the account is.
+	redact.Default.SetMode(redact.Mode(seatCfg.Global.Redaction.SchoolID))
+	logger, closeLog, err := logging.New(logging.Config{
+		Level:  seatCfg.Global.Log.Level,
+		Format: seatCfg.Global.Log.Format,
+		Dir:    seatCfg.Global.Log.Dir,
+	}, logOutput(opts))
+	if err != nil {
+		return fmt.Errorf("invalid log settings in user_config.yml: %w", err)
+	}
+	defer closeLog.Close()
+	slog.SetDefault(logger)
+	ctx = logging.With(ctx, logging.Account(userInfo.SchoolID))
+	logger = logging.From(ctx)
+
+	classifier, err := booker.NewClassifier(responseRules(seatCfg.Global.ResponseRules))
+	if err != nil {
+		return fmt.Errorf("invalid response_rules in user_config.yml: %w", err)
+	}
+	loginProvider, err := sso.NewProvider(seatCfg.Global.LoginProvider, seatCfg.Global.DebugLogin)
+	if err != nil {
+		return fmt.Errorf("invalid login_provider in user_config.yml: %w", err)
+	}
+	sso.SetProvider(loginProvider)
+	httpTransport, resolver, err := newHTTPTransport(seatCfg.Global.HTTP)
+	if err != nil {
+		return fmt.Errorf("invalid http settings in user_config.yml: %w", err)
+	}
+	if opts.dryRun {
+		dryRun, err := newDryRunTransport(httpTransport, opts)
+		if err != nil {
+			return err
+		}
+		defer dryRun.Close()
+		httpTransport = dryRun
+		logger.Warn("Dry run: no booking request will reach the library", "mock", opts.mock, "record", opts.record)
+	}
+	if cbCfg := seatCfg.Global.CircuitBreaker; cbCfg.Enable {
+		breakers := installCircuitBreaker(cbCfg, httpTransport)
+		defer logBreakerStats(breakers)
+	} else {
+		sso.SetTransport(httpTransport)
 	}
-	log.Println("Configs and seat map loaded.")
-	log.Printf("Loaded user config for SchoolID: %s", userInfo.SchoolID)
+	logger.Info("Configs and seat map loaded")
 
 	// --- 2. Validate Credentials ---
-	log.Println("Validating user credentials...")
-	validationFunc := func() error {
-		return sso.ValidateCredentials(userInfo.SchoolID, userInfo.Password)
+	// The validating login is kept as the session, so unless it expires before the
+	// booking window there is only one CAS login per run. A saved session that is
+	// still valid skips even that one.
+	status.set("validating credentials")
+	session := sso.NewSession(userInfo.SchoolID, userInfo.Password, seatCfg.Global.MaxRelogins)
+	observeLogins(session, userInfo.SchoolID)
+	if cacheCfg := seatCfg.Global.SessionCache; cacheCfg.Enable {
+		session.Persist(filepath.Join(cacheCfg.Dir, userInfo.SchoolID+".session"))
 	}
-	if err := retry.WithRetry(validationFunc, 3, 2*time.Second); err != nil {
-		log.Fatalf("Credential validation failed after multiple retries: %v. Please check your user_info.yml.", err)
+	if session.Resume(ctx, checkSession) {
+		logger.Info("Saved session is valid, skipping credential validation")
+	} else {
+		logger.Info("Validating user credentials")
+		validationFunc := func() error { return session.Login(ctx) }
+		if err := credentialPolicy.Do(ctx, validationFunc); err != nil {
+			return fmt.Errorf("credential validation failed after multiple retries: %w. Please check your user_info.yml", err)
+		}
+		logger.Info("User credentials are valid")
 	}
-	log.Println("User credentials are valid.")
 
 	// --- 3. Determine Today's Booking Task ---
-	weekdayMap := map[time.Weekday]string{
-		time.Sunday: "周日", time.Monday: "周一", time.Tuesday: "周二",
-		time.Wednesday: "周三", time.Thursday: "周四", time.Friday: "周五",
-		time.Saturday: "周六",
-	}
 	//通过调用时间函数返回值，匹配哈希表，得到今天是周几的中文
-	todayWeekdayStr := weekdayMap[time.Now().Weekday()]
+	todayWeekdayStr := weekdayNames[time.Now().Weekday()]
 	//通过处理函数获取当前要请求的位置
 	dayConfig, ok := seatCfg.WeekConfig[todayWeekdayStr]
 	if !ok || !dayConfig.Enable || len(dayConfig.Seats) == 0 {
-		log.Printf("Booking is not enabled for today (%s) or no seats configured. Exiting.", todayWeekdayStr)
-		return
+		logger.Info("Booking is not enabled for today or no seats configured, exiting", "weekday", todayWeekdayStr)
+		status.set("no booking task for today (%s)", todayWeekdayStr)
+		status.record(func(e *history.Entry) {
+			e.Weekday, e.Outcome, e.Detail = todayWeekdayStr, history.Skipped, "no booking task for today"
+		})
+		return nil
 	}
-	log.Printf("Found booking task for today (%s): Run at %d:%02d to book one of %d seat(s).",
-		todayWeekdayStr, dayConfig.RunAtHour, dayConfig.RunAtMinute, len(dayConfig.Seats))
-
-	bookingDayForLog := time.Now().AddDate(0, 0, 2)
-	targetTime := time.Date(bookingDayForLog.Year(), bookingDayForLog.Month(), bookingDayForLog.Day(), dayConfig.BookStartHour, 0, 0, 0, time.Local)
-	log.Printf("Task for SchoolID [%s]: Booking for %s, from %s for %d hours. Seats: %v",
-		userInfo.SchoolID,
-		targetTime.Format("2006-01-02"),
-		targetTime.Format("15:04"),
-		dayConfig.Duration,
-		dayConfig.Seats)
+	status.record(func(e *history.Entry) { e.Weekday, e.Room = todayWeekdayStr, dayConfig.Name })
+	ctx = logging.With(ctx, logging.Room(dayConfig.Name))
+	logger = logging.From(ctx)
+	targetTime := dayConfig.BeginTime(time.Now().AddDate(0, 0, 2))
+	logger.Info("Found booking task for today",
+		"weekday", todayWeekdayStr,
+		"run_at", fmt.Sprintf("%02d:%02d", dayConfig.RunAtHour, dayConfig.RunAtMinute),
+		"date", targetTime.Format("2006-01-02"),
+		"begin", targetTime.Format("15:04"),
+		"duration", dayConfig.Duration.String(),
+		"seats", dayConfig.Seats)
 
 	// --- 4. Define Time Windows ---
 	now := time.Now()
 	officialBookTime := time.Date(now.Year(), now.Month(), now.Day(), dayConfig.RunAtHour, dayConfig.RunAtMinute, 0, 0, time.Local)
+	if !opts.openAt.IsZero() {
+		// A rehearsal has no official time to align with the server's clock.
+		officialBookTime = opts.openAt
+		logger.Info("Rehearsing with a booking time of our own", "at", officialBookTime.Format("15:04:05"))
+	} else if syncCfg := seatCfg.Global.TimeSync; syncCfg.Enable {
+		status.set("measuring server clock offset")
+		officialBookTime = alignToServerClock(ctx, &http.Client{Transport: httpTransport}, syncCfg, officialBookTime)
+	}
 	preemptTime := officialBookTime.Add(-time.Duration(seatCfg.Global.PreemptSeconds) * time.Second)
 	fallbackEndTime := officialBookTime.Add(fallbackWindow)
+	if opts.mock {
+		stopMock, err := startMockServer(ctx, officialBookTime, dayConfig.Name, dayConfig.Seats, opts.mockTaken)
+		if err != nil {
+			return err
+		}
+		defer stopMock()
+	}
 
-	log.Printf("Attack Phase: %s -> %s (Primary Seat)", preemptTime.Format("15:04:05"), officialBookTime.Format("15:04:05"))
-	log.Printf("Fallback Phase: %s -> %s (All Seats)", officialBookTime.Format("15:04:05"), fallbackEndTime.Format("15:04:05"))
+	view.SetWindow(dayConfig.Name, dayConfig.Seats, preemptTime, officialBookTime, fallbackEndTime)
+	logger.Info("Booking windows planned", logging.Phase("attack"), "start", preemptTime.Format("15:04:05.000"), "end", officialBookTime.Format("15:04:05.000"))
+	logger.Info("Booking windows planned", logging.Phase("fallback"), "start", officialBookTime.Format("15:04:05.000"), "end", fallbackEndTime.Format("15:04:05.000"))
 
-	// --- 5. Wait for the first window ---
-	if now.Before(preemptTime) {
-		time.Sleep(preemptTime.Sub(now))
+	// --- 5. Wait until it is time to log in ---
+	// With pre-warm enabled we log in ahead of the preempt time so that a slow SSO
+	// cannot eat into the booking window.
+	prewarm := time.Duration(seatCfg.Global.PrewarmMinutes) * time.Minute
+	loginTime := preemptTime.Add(-prewarm)
+	status.set("waiting until %s to log in", loginTime.Format("15:04:05"))
+	if err := retry.Sleep(ctx, time.Until(loginTime)); err != nil {
+		return err
 	}
 	if time.Now().After(fallbackEndTime) {
-		log.Println("Booking window has already passed. Exiting.")
-		return
+		logger.Info("Booking window has already passed, exiting")
+		status.set("booking window had already passed")
+		status.record(func(e *history.Entry) { e.Outcome, e.Detail = history.Skipped, "booking window had already passed" })
+		return nil
 	}
+	// Nothing network-bound may outlive the booking window.
+	windowCtx, cancelWindow := context.WithDeadline(ctx, fallbackEndTime)
+	defer cancelWindow()
 
 	// --- 6. Login and Prepare ---
-	log.Println("Booking window opened. Logging in...")
-	var client *http.Client
-	loginFunc := func() error {
-		var loginErr error
-		client, _, loginErr = sso.Login(userInfo.SchoolID, userInfo.Password)
-		return loginErr
-	}
-	// Retry login for up to a minute to handle temporary service unavailability.
-	// 20 attempts with a 3-second delay gives a ~1 minute window.
-	if err := retry.WithRetry(loginFunc, 20, 3*time.Second); err != nil {
-		log.Fatalf("Login failed after persistent retries for ~1 minute: %v", err)
-	}
-	loggedInUser, err := user.GetUserInfo(client)
+	if resolver != nil {
+		if err := resolver.PreResolve(windowCtx, seatCfg.Global.HTTP.PreResolve); err != nil {
+			logger.Warn("DNS pre-resolution failed, lookups will happen on demand", logging.Err(err))
+		}
+	}
+	if prewarm > 0 {
+		logger.Info("Pre-warm phase started, logging in", "prewarm_minutes", seatCfg.Global.PrewarmMinutes)
+	} else {
+		logger.Info("Booking window opened, logging in")
+	}
+	status.set("logging in")
+	view.SetPhase("login")
+	if session.Resume(windowCtx, checkSession) {
+		logger.Info("Session is still valid, skipping login")
+	} else {
+		loginFunc := func() error { return session.Login(windowCtx) }
+		// Retry login for up to a minute to handle temporary service unavailability.
+		if err := loginPolicy.Do(windowCtx, loginFunc); err != nil {
+			return fmt.Errorf("login failed after persistent retries: %w", err)
+		}
+	}
+	var loggedInUser *user.UserInfo
+	err = userInfoPolicy.Do(windowCtx, func() error {
+		var infoErr error
+		loggedInUser, infoErr = user.GetUserInfo(windowCtx, session.Client())
+		return infoErr
+	})
 	if err != nil {
-		log.Fatalf("User info fetch failed: %v", err)
+		return fmt.Errorf("user info fetch failed: %w", err)
 	}
-	log.Printf("Logged in as SchoolID [%s] (UID: %s). Starting high-frequency requests...", userInfo.SchoolID, loggedInUser.UID)
+	logger.Info("Logged in", "uid", loggedInUser.UID)
+	if time.Now().Before(preemptTime) {
+		view.SetPhase("prewarm")
+		status.set("pre-warming session until %s", preemptTime.Format("15:04:05"))
+		keepWarm(windowCtx, session, preemptTime, time.Duration(seatCfg.Global.KeepAliveSeconds)*time.Second)
+	}
+	// Open one connection per concurrent request now, so that the first booking
+	// requests do not pay for TCP and TLS handshakes.
+	if err := transport.Warm(windowCtx, session.Client(), warmURL, seatCfg.Global.MaxInFlight); err != nil {
+		logger.Warn("Connection pre-warm failed", logging.Err(err))
+	}
+	logger.Info("Starting high-frequency requests")
 
 	// --- 7. Execute Phased Booking ---
-	if success, seat := executeBookingPhase(client, userInfo, loggedInUser, &dayConfig, preemptTime, officialBookTime, true); success {
-		bookingDay := time.Now().AddDate(0, 0, 2)
-		bookTime := time.Date(bookingDay.Year(), bookingDay.Month(), bookingDay.Day(), dayConfig.BookStartHour, 0, 0, 0, time.Local)
-		log.Printf("BOOKING SUCCESSFUL for SchoolID [%s] in Attack Phase! Seat '%s' in room '%s' booked for %s from %s for %d hours.",
-			userInfo.SchoolID,
-			seat,
-			dayConfig.Name,
-			bookTime.Format("2006-01-02"),
-			bookTime.Format("15:04"),
-			dayConfig.Duration)
-		return
-	}
-	if success, seat := executeBookingPhase(client, userInfo, loggedInUser, &dayConfig, officialBookTime, fallbackEndTime, false); success {
-		bookingDay := time.Now().AddDate(0, 0, 2)
-		bookTime := time.Date(bookingDay.Year(), bookingDay.Month(), bookingDay.Day(), dayConfig.BookStartHour, 0, 0, 0, time.Local)
-		log.Printf("BOOKING SUCCESSFUL for SchoolID [%s] in Fallback Phase! Seat '%s' in room '%s' booked for %s from %s for %d hours.",
-			userInfo.SchoolID,
-			seat,
-			dayConfig.Name,
-			bookTime.Format("2006-01-02"),
-			bookTime.Format("15:04"),
-			dayConfig.Duration)
-		return
-	}
-
-	log.Println("Seat Killer finished: all attempts failed within all windows.")
-}
-
-// executeBookingPhase runs the booking loop for a specific time window and seat strategy.
-// Returns true if booking was successful.
-func executeBookingPhase(client *http.Client, cfgUser *config.UserInfo, loggedInUser *user.UserInfo, dayCfg *config.DayConfig, start, end time.Time, primaryOnly bool) (bool, string) {
-	ticker := time.NewTicker(requestInterval)
-	defer ticker.Stop()
-
-	seatsToTry := dayCfg.Seats
-	if primaryOnly {
-		seatsToTry = []string{dayCfg.Seats[0]}
-		log.Printf("--- Entering Attack Phase for SchoolID [%s]: Focusing on primary seat %s ---", cfgUser.SchoolID, seatsToTry[0])
-	} else {
-		log.Printf("--- Entering Fallback Phase for SchoolID [%s]: Trying all %d seats ---", cfgUser.SchoolID, len(seatsToTry))
+	// Real runs share their request budget with the other accounts on this
+	// machine; a dry run's requests never reach the library, so it keeps its own.
+	var budget *ratelimit.Store
+	if !opts.dryRun {
+		if budget, err = sharedBudget(); err != nil {
+			logger.Warn("Cannot share the request budget with other accounts, pacing this run only", logging.Err(err))
+		}
 	}
-
-	bookingDay := time.Now().AddDate(0, 0, 2)
-
-	for t := range ticker.C {
-		if t.Before(start) {
-			continue
+	task := &bookingTask{
+		session:      session,
+		account:      accountLabel(userInfo.SchoolID),
+		loggedInUser: loggedInUser,
+		dayCfg:       &dayConfig,
+		classifier:   classifier,
+		maxInFlight:  seatCfg.Global.MaxInFlight,
+		limiter:      newLimiter(seatCfg.Global.RateLimit, budget),
+		ladder:       newDurationLadder(dayConfig.DurationCandidates()),
+		dropped:      make(map[string]bool),
+		accepted:     make(map[string]acceptedBooking),
+		view:         view,
+	}
+	phases := []struct {
+		name        string
+		start, end  time.Time
+		primaryOnly bool
+	}{
+		{"Attack", preemptTime, officialBookTime, true},
+		{"Fallback", officialBookTime, fallbackEndTime, false},
+	}
+	for _, phase := range phases {
+		status.set("%s phase (%s -> %s)", phase.name, phase.start.Format("15:04:05"), phase.end.Format("15:04:05"))
+		phaseName := strings.ToLower(phase.name)
+		view.SetPhase(phaseName)
+		phaseCtx := logging.With(windowCtx, logging.Phase(phaseName))
+		outcome := executeBookingPhase(phaseCtx, task, phaseName, phase.start, phase.end, phase.primaryOnly)
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
-		if t.After(end) {
-			return false, ""
+		if outcome.success {
+			timeToFirstSuccess.Set(time.Since(officialBookTime).Seconds(), accountLabel(userInfo.SchoolID))
+			bookTime := dayConfig.BeginTime(time.Now().AddDate(0, 0, 2))
+			logging.From(phaseCtx).Info("BOOKING SUCCESSFUL",
+				logging.Seat(outcome.seat),
+				"date", bookTime.Format("2006-01-02"),
+				"begin", bookTime.Format("15:04"),
+				"duration", config.Duration(outcome.duration).String())
+			seatID, _ := mapper.GetSeatID(dayConfig.Name, outcome.seat)
+			status.record(func(e *history.Entry) {
+				e.Outcome, e.Seat, e.SeatID, e.BookingID, e.Phase = history.Booked, outcome.seat, seatID, outcome.bookingID, phaseName
+				e.Begin, e.Duration = bookTime, outcome.duration
+			})
+			for _, other := range outcome.also {
+				// Accepted before its attempt could be cancelled: the account holds it too.
+				logging.From(phaseCtx).Warn("Another seat was booked as well, cancel it if you do not need it",
+					logging.Seat(other.seat),
+					"booking_id", other.id,
+					"duration", config.Duration(other.duration).String())
+				otherID, _ := mapper.GetSeatID(dayConfig.Name, other.seat)
+				status.also(history.Entry{
+					Outcome: history.Booked, Seat: other.seat, SeatID: otherID, BookingID: other.id, Phase: phaseName,
+					Begin: bookTime, Duration: other.duration,
+					Detail: fmt.Sprintf("also booked while booking seat %s", outcome.seat),
+				})
+			}
+			summary := fmt.Sprintf("booked seat '%s' in room '%s' for %s (%s phase)", outcome.seat, dayConfig.Name, config.Duration(outcome.duration), phase.name)
+			if len(outcome.also) > 0 {
+				summary += fmt.Sprintf(", plus %d more seat(s) accepted at the same time; cancel the ones you do not need", len(outcome.also))
+			}
+			status.set("%s", summary)
+			return nil
 		}
-
-		// Calculate delay to spread requests evenly within the interval to avoid rate limiting
-		stepDelay := time.Duration(0)
-		if len(seatsToTry) > 1 {
-			// Use slightly less than the full interval to ensure we don't overrun the ticker too much
-			stepDelay = (requestInterval - 50*time.Millisecond) / time.Duration(len(seatsToTry))
+		if outcome.stopReason != nil {
+			logging.From(phaseCtx).Warn("Seat Killer stopped", logging.Err(outcome.stopReason))
+			status.set("stopped in %s phase: %v", phase.name, outcome.stopReason)
+			status.record(func(e *history.Entry) {
+				e.Outcome, e.Phase, e.Detail = history.Stopped, phaseName, outcome.stopReason.Error()
+			})
+			return nil
 		}
+	}
 
-		for i, seatNum := range seatsToTry {
-			// Add delay between seats (but not before the first one in this batch)
-			if i > 0 && stepDelay > 0 {
-				time.Sleep(stepDelay)
-			}
+	logger.Warn("Seat Killer finished: all attempts failed within all windows")
+	status.set("no seat booked, all attempts failed")
+	status.record(func(e *history.Entry) { e.Outcome, e.Detail = history.Failed, "all attempts failed within all windows" })
+	return nil
+}
 
-			seatID, err := mapper.GetSeatID(dayCfg.Name, seatNum)
-			if err != nil {
-				log.Printf("Cannot find seat '%s' in room '%s', skipping.", seatNum, dayCfg.Name)
-				continue
-			}
+// logOutput is where run logs to the terminal: the dashboard when there is one, else stderr.
+func logOutput(opts runOptions) io.Writer {
+	if opts.board != nil {
+		return opts.board
+	}
+	return os.Stderr
+}
 
-			bookTime := time.Date(bookingDay.Year(), bookingDay.Month(), bookingDay.Day(), dayCfg.BookStartHour, 0, 0, 0, time.Local)
-			duration := time.Duration(dayCfg.Duration) * time.Hour
-
-			log.Printf("Attempting to book for SchoolID [%s]: room '%s', seat '%s' (%d)", cfgUser.SchoolID, dayCfg.Name, seatNum, seatID)
-			var result *booker.BookResponseData
-			bookReq := &booker.BookingRequest{
-				Client:    client,
-				UserID:    loggedInUser.UID,
-				SeatID:    seatID,
-				BeginTime: bookTime,
-				Duration:  duration,
-			}
-			bookFunc := func() error {
-				var bookErr error
-				result, bookErr = booker.BookSeat(bookReq)
-				// If there's a booking error but the response indicates a non-retryable server message, wrap it.
-				if bookErr == nil && !result.IsSuccess() && result.MESSAGE != "" {
-					// Let's consider messages like "request too frequent" or "seat taken" as unretryable for the *immediate* retry.
-					// The outer ticker loop will handle the next attempt after the interval.
-					return retry.WrapUnretryable(fmt.Errorf("booking failed with server message: [%v] %s", result.CODE, result.MESSAGE))
-				}
-				return bookErr
-			}
+// checkSession is the cheap validity check for an existing session.
+func checkSession(ctx context.Context, client *http.Client) error {
+	_, err := user.GetUserInfo(ctx, client)
+	return err
+}
 
-			if err := retry.WithRetry(bookFunc, 2, 100*time.Millisecond); err != nil {
-				// Log the final error after retries, but don't stop the whole process.
-				log.Printf("Booking attempt for seat %d failed after retries: %v", seatID, err)
-				continue
-			}
+// newHTTPTransport builds the transport shared by all SSO, library and time sync traffic.
+func newHTTPTransport(httpCfg config.HTTPConfig) (http.RoundTripper, *transport.Resolver, error) {
+	dnsTTL := time.Duration(httpCfg.DNSCacheSeconds) * time.Second
+	if httpCfg.DNSCacheSeconds < 0 {
+		dnsTTL = 0
+	}
+	return transport.New(transport.Config{
+		RequestTimeout:      time.Duration(httpCfg.RequestTimeoutMS) * time.Millisecond,
+		DialTimeout:         time.Duration(httpCfg.DialTimeoutMS) * time.Millisecond,
+		TLSHandshakeTimeout: time.Duration(httpCfg.TLSTimeoutMS) * time.Millisecond,
+		IdleConnTimeout:     time.Duration(httpCfg.IdleConnSeconds) * time.Second,
+		MaxIdleConnsPerHost: httpCfg.MaxIdleConnsPerHost,
+		DisableHTTP2:        httpCfg.DisableHTTP2,
+		Proxy:               httpCfg.Proxy,
+		DNSCacheTTL:         dnsTTL,
+	})
+}
 
-			log.Printf("Booking result for SchoolID [%s]: [%v] %s", cfgUser.SchoolID, result.CODE, result.MESSAGE)
-			if result.IsSuccess() {
-				return true, seatNum
-			}
+// installCircuitBreaker routes all SSO and library traffic through per-host breakers on top of base.
+func installCircuitBreaker(cbCfg config.CircuitBreakerConfig, base http.RoundTripper) *breaker.Set {
+	breakers := breaker.NewSet(breaker.Config{
+		FailureThreshold: cbCfg.FailureThreshold,
+		OpenTimeout:      time.Duration(cbCfg.OpenSeconds) * time.Second,
+		HalfOpenProbes:   cbCfg.HalfOpenProbes,
+	})
+	breakers.OnStateChange = func(host string, from, to breaker.State) {
+		slog.Warn("Circuit breaker state changed", "host", host, "from", from.String(), "to", to.String())
+		observeBreaker(host, from, to)
+	}
+	sso.SetTransport(&breaker.Transport{Base: base, Set: breakers, LoginHost: booker.SSOHost, LoginPageMarkers: booker.LoginPageMarkers})
+	return breakers
+}
+
+// logBreakerStats summarises hosts whose breaker tripped during the run.
+func logBreakerStats(breakers *breaker.Set) {
+	for host, stats := range breakers.Stats() {
+		if stats.Opens > 0 {
+			slog.Info("Circuit breaker summary", "host", host, "opens", stats.Opens, "rejected", stats.Rejected, "state", stats.State.String())
 		}
 	}
-	return false, "" // Should not be reached if end time is handled correctly
+}
+
+// alignToServerClock converts officialBookTime, meant on the server's clock, to the
+// local instant at which the server's clock shows it. On failure the local time is kept.
+func alignToServerClock(ctx context.Context, client *http.Client, syncCfg config.TimeSyncConfig, officialBookTime time.Time) time.Time {
+	target := syncCfg.URL
+	if target == "" {
+		target = timesync.DefaultURL
+	}
+	logger := logging.From(ctx)
+	logger.Info("Estimating server clock offset", "url", target, "samples", syncCfg.Samples)
+	estimate, err := timesync.Measure(ctx, client, target, syncCfg.Samples)
+	if err != nil {
+		logger.Warn("Time sync failed, falling back to the local clock", logging.Err(err))
+		return officialBookTime
+	}
+	logger.Info("Server clock offset estimated",
+		"offset_ms", estimate.Offset.Milliseconds(),
+		"uncertainty_ms", estimate.Uncertainty.Milliseconds(),
+		"rtt_ms", estimate.RTT.Milliseconds(),
+		"samples", estimate.Samples)
+	return estimate.ToLocal(officialBookTime)
 }
@@ -3,6 +3,7 @@ package mapper
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
 	"regexp"
 	"strconv"
@@ -54,6 +55,7 @@ func LoadSeatMap(path string) (SeatMapper, error) {
 		return nil, fmt.Errorf("error reading seat map file: %w", err)
 	}
 	seatMap = mapper
+	slog.Debug("Seat map loaded", "path", path, "rooms", len(mapper))
 	return mapper, nil
 }
 
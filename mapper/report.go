@@ -0,0 +1,105 @@
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// seatItemType is the ui_type of the nodes describing a room and its seats.
+const seatItemType = "ht.Seat.RecommendSeatItem"
+
+// Room is a room and its seats as found in the library's seat search page.
+type Room struct {
+	Name  string
+	Seats []SeatInfo
+}
+
+type seatPOI struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type roomNode struct {
+	UIType   string `json:"ui_type"`
+	RoomName string `json:"roomName"`
+	SeatMap  *struct {
+		POIs []seatPOI `json:"POIs"`
+	} `json:"seatMap"`
+}
+
+type seatPage struct {
+	AllContent struct {
+		Children []struct {
+			Children struct {
+				Children []json.RawMessage `json:"children"`
+			} `json:"children"`
+		} `json:"children"`
+	} `json:"allContent"`
+}
+
+// ParseSeatPage extracts the rooms with seats from the JSON of the seat search
+// page, as saved in cache/seat_data_cache.json.
+func ParseSeatPage(data []byte) ([]Room, error) {
+	var page seatPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse seat page JSON: %w", err)
+	}
+	var rooms []Room
+	for _, block := range page.AllContent.Children {
+		for _, raw := range block.Children.Children {
+			var node roomNode
+			if json.Unmarshal(raw, &node) != nil || node.UIType != seatItemType || node.SeatMap == nil {
+				continue
+			}
+			room := Room{Name: node.RoomName}
+			for _, poi := range node.SeatMap.POIs {
+				seatID, err := strconv.Atoi(poi.ID)
+				if err != nil {
+					return nil, fmt.Errorf("room '%s': invalid seat id %q", node.RoomName, poi.ID)
+				}
+				room.Seats = append(room.Seats, SeatInfo{SeatID: seatID, Title: poi.Title})
+			}
+			if len(room.Seats) > 0 {
+				rooms = append(rooms, room)
+			}
+		}
+	}
+	if len(rooms) == 0 {
+		return nil, fmt.Errorf("no rooms with seats found in the seat page")
+	}
+	return rooms, nil
+}
+
+// WriteReport writes rooms in the seat map format read by LoadSeatMap, rooms
+// sorted by name and seats by their numeric title.
+func WriteReport(w io.Writer, rooms []Room) error {
+	rooms = append([]Room(nil), rooms...)
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+
+	if _, err := fmt.Fprint(w, "# Seat ID to Title Mapping Report\n\n"); err != nil {
+		return err
+	}
+	for _, room := range rooms {
+		seats := append([]SeatInfo(nil), room.Seats...)
+		sort.SliceStable(seats, func(i, j int) bool {
+			titleI, _ := strconv.Atoi(seats[i].Title)
+			titleJ, _ := strconv.Atoi(seats[j].Title)
+			return titleI < titleJ
+		})
+		if _, err := fmt.Fprintf(w, "# Room: %s\n", room.Name); err != nil {
+			return err
+		}
+		for _, seat := range seats {
+			if _, err := fmt.Fprintf(w, "SeatID: %d, Title: %s\n", seat.SeatID, seat.Title); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package mapper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const samplePage = `{"allContent": {"children": [
+	{"ui_type": "ht.Seat.SysTipBarBlock"},
+	{"children": {"children": [
+		{"ui_type": "ht.Seat.RecommendSeatItem", "roomName": "二楼", "seatMap": {"POIs": [{"id": "202", "title": "10"}, {"id": "201", "title": "2"}]}},
+		{"ui_type": "com.Other", "roomName": "忽略"},
+		{"ui_type": "ht.Seat.RecommendSeatItem", "roomName": "一楼", "seatMap": {"POIs": [{"id": "101", "title": "1"}]}},
+		{"ui_type": "ht.Seat.RecommendSeatItem", "roomName": "空房间", "seatMap": {"POIs": []}}
+	]}}
+]}}`
+
+func TestParseSeatPageAndWriteReport(t *testing.T) {
+	rooms, err := ParseSeatPage([]byte(samplePage))
+	if err != nil {
+		t.Fatalf("ParseSeatPage 返回错误: %v", err)
+	}
+	if len(rooms) != 2 {
+		t.Fatalf("期望 2 个有座位的房间, 实际为 %+v", rooms)
+	}
+
+	path := filepath.Join(t.TempDir(), "seat_report.txt")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteReport(f, rooms); err != nil {
+		t.Fatalf("WriteReport 返回错误: %v", err)
+	}
+	f.Close()
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# Room: 二楼\nSeatID: 201, Title: 2\nSeatID: 202, Title: 10\n") {
+		t.Errorf("座位应按编号排序:\n%s", data)
+	}
+	if strings.Index(string(data), "一楼") > strings.Index(string(data), "二楼") {
+		t.Errorf("房间应按名称排序:\n%s", data)
+	}
+
+	loaded, err := LoadSeatMap(path)
+	if err != nil {
+		t.Fatalf("LoadSeatMap 返回错误: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("期望读回 2 个房间, 实际为 %d", len(loaded))
+	}
+	if id, err := GetSeatID("二楼", "10"); err != nil || id != 202 {
+		t.Errorf("期望座位 10 的 ID 为 202, 实际为 %d (%v)", id, err)
+	}
+}
+
+func TestParseSeatPageRejectsEmptyPage(t *testing.T) {
+	if _, err := ParseSeatPage([]byte(`{"allContent": {"children": []}}`)); err == nil {
+		t.Error("期望没有房间时报错")
+	}
+	if _, err := ParseSeatPage([]byte(`not json`)); err == nil {
+		t.Error("期望无效 JSON 报错")
+	}
+}
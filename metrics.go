@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"seat-killer/booker"
+	"seat-killer/breaker"
+	"seat-killer/logging"
+	"seat-killer/metrics"
+	"seat-killer/redact"
+	"seat-killer/retry"
+	"seat-killer/sso"
+)
+
+// Metrics served on the optional metrics endpoint. Accounts are labelled with
+// their redacted school ID.
+var (
+	bookingAttempts = metrics.Default.NewCounter("seatkiller_booking_attempts_total",
+		"Booking attempts by outcome, server code and phase.", "account", "phase", "outcome", "code")
+	requestDuration = metrics.Default.NewHistogram("seatkiller_request_duration_seconds",
+		"Latency of library and SSO requests.", nil, "operation")
+	loginAttempts = metrics.Default.NewCounter("seatkiller_login_attempts_total",
+		"CAS login attempts by result.", "account", "result")
+	retries = metrics.Default.NewCounter("seatkiller_retries_total",
+		"Retries scheduled by the retry policies.", "policy")
+	timeToFirstSuccess = metrics.Default.NewGauge("seatkiller_time_to_first_success_seconds",
+		"Time from the official booking time to the successful booking of the last run.", "account")
+	nextRunTimestamp = metrics.Default.NewGauge("seatkiller_next_run_timestamp_seconds",
+		"Unix time at which the next run starts.", "account")
+	breakerState = metrics.Default.NewGauge("seatkiller_circuit_breaker_state",
+		"State of the circuit breaker of each host: 0 closed, 1 open, 2 half-open.", "host")
+	breakerTransitions = metrics.Default.NewCounter("seatkiller_circuit_breaker_transitions_total",
+		"Circuit breaker state changes by host.", "host", "from", "to")
+)
+
+// accountLabel is the metrics label of an account; it is masked like in logs.
+func accountLabel(schoolID string) string {
+	return redact.String(schoolID)
+}
+
+// countRetries returns a retry observer that counts the retries of policy.
+func countRetries(policy string) func(retry.Attempt) {
+	return func(a retry.Attempt) {
+		if !a.Final {
+			retries.Inc(policy)
+		}
+	}
+}
+
+// observeLogins records every CAS login of session.
+func observeLogins(session *sso.Session, schoolID string) {
+	account := accountLabel(schoolID)
+	session.OnLogin = func(elapsed time.Duration, err error) {
+		requestDuration.Observe(elapsed.Seconds(), "login")
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		loginAttempts.Inc(account, result)
+	}
+}
+
+// observeBreaker records a state change of the circuit breaker of host.
+func observeBreaker(host string, from, to breaker.State) {
+	breakerState.Set(float64(to), host)
+	breakerTransitions.Inc(host, from.String(), to.String())
+}
+
+// bookingOutcome maps the result of one booking attempt to the outcome and code labels.
+func bookingOutcome(result *booker.BookResponseData, err error) (outcome, code string) {
+	var refusal *booker.BookingError
+	switch {
+	case err == nil:
+		return "success", fmt.Sprint(result.CODE)
+	case errors.As(err, &refusal):
+		return string(refusal.Kind), refusal.Code
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled", ""
+	}
+	return "error", ""
+}
+
+// serveMetrics serves /metrics on addr until ctx is done.
+func serveMetrics(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	slog.Info("Serving metrics", "addr", listener.Addr().String())
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics server stopped", logging.Err(err))
+		}
+	}()
+	return nil
+}
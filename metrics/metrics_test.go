@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	attempts := r.NewCounter("seatkiller_booking_attempts_total", "Booking attempts.", "outcome", "code")
+	nextRun := r.NewGauge("seatkiller_next_run_timestamp_seconds", "Next run.", "account")
+	latency := r.NewHistogram("seatkiller_request_duration_seconds", "Latency.", []float64{0.1, 1}, "operation")
+
+	attempts.Inc("seat_taken", "1")
+	attempts.Inc("seat_taken", "1")
+	attempts.Inc("success", `a"b`)
+	nextRun.Set(1760000000, "23****01")
+	latency.Observe(0.05, "book")
+	latency.Observe(0.5, "book")
+	latency.Observe(3, "book")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	got := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE seatkiller_booking_attempts_total counter\n",
+		`seatkiller_booking_attempts_total{outcome="seat_taken",code="1"} 2` + "\n",
+		`seatkiller_booking_attempts_total{outcome="success",code="a\"b"} 1` + "\n",
+		`seatkiller_next_run_timestamp_seconds{account="23****01"} 1.76e+09` + "\n",
+		`seatkiller_request_duration_seconds_bucket{operation="book",le="0.1"} 1` + "\n",
+		`seatkiller_request_duration_seconds_bucket{operation="book",le="1"} 2` + "\n",
+		`seatkiller_request_duration_seconds_bucket{operation="book",le="+Inf"} 3` + "\n",
+		`seatkiller_request_duration_seconds_sum{operation="book"} 3.55` + "\n",
+		`seatkiller_request_duration_seconds_count{operation="book"} 3` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("输出缺少 %q\n完整输出:\n%s", want, got)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type 错误: %s", ct)
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("标签数量不符时应 panic")
+		}
+	}()
+	NewRegistry().NewCounter("c", "help", "a").Inc()
+}
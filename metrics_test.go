@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"seat-killer/breaker"
+	"seat-killer/metrics"
+)
+
+func TestObserveBreaker(t *testing.T) {
+	const host = "breaker-test.example.com"
+	observeBreaker(host, breaker.Closed, breaker.Open)
+	observeBreaker(host, breaker.Open, breaker.HalfOpen)
+	observeBreaker(host, breaker.HalfOpen, breaker.Open)
+
+	if got := breakerTransitions.Value(host, "closed", "open"); got != 1 {
+		t.Errorf("期望 closed->open 计数为 1, 实际为 %v", got)
+	}
+	if got := breakerTransitions.Value(host, "half-open", "open"); got != 1 {
+		t.Errorf("期望 half-open->open 计数为 1, 实际为 %v", got)
+	}
+	var out strings.Builder
+	if _, err := metrics.Default.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	if want := `seatkiller_circuit_breaker_state{host="` + host + `"} 1`; !strings.Contains(out.String(), want) {
+		t.Errorf("期望输出包含 %s:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,187 @@
+// Package mockserver imitates the library's booking API, so that a dry run can
+// exercise the whole booking flow, refusals included, without touching real seats.
+package mockserver
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"seat-killer/booker"
+)
+
+// Messages returned for refusals; they are matched by booker.DefaultClassifier.
+const (
+	MsgNotOpen       = "未到预约时间"
+	MsgSeatTaken     = "该座位已被预约"
+	MsgAlreadyBooked = "您已有预约"
+	MsgBadToken      = "api-token 校验失败"
+	MsgBadRequest    = "参数错误"
+)
+
+// Request is a booking request as received by the server.
+type Request struct {
+	Received  time.Time
+	SeatID    int
+	UserID    string
+	BeginTime time.Time
+	Duration  time.Duration
+}
+
+// Server answers booking requests like the library does: requests before OpenAt
+// are refused as not open, taken seats are refused, and the first valid request
+// of a user wins its seat; that user's later requests are refused as already booked.
+type Server struct {
+	// OpenAt is when booking opens. The zero value means it is already open.
+	OpenAt time.Time
+	// Now returns the current time; defaults to time.Now.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	taken    map[int]bool
+	bookings map[string]int // user ID -> seat ID
+	requests []Request
+	srv      *http.Server
+}
+
+// New returns a server that opens booking at openAt.
+func New(openAt time.Time) *Server {
+	return &Server{OpenAt: openAt, taken: make(map[int]bool), bookings: make(map[string]int)}
+}
+
+// Take marks seats as already booked by someone else.
+func (s *Server) Take(seatIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range seatIDs {
+		s.taken[id] = true
+	}
+}
+
+// Requests returns the booking requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Booking returns the seat booked by a user.
+func (s *Server) Booking(userID string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seatID, ok := s.bookings[userID]
+	return seatID, ok
+}
+
+// Start serves on a free port of the loopback interface and returns the booking
+// endpoint to pass to booker.SetEndpoint.
+func (s *Server) Start() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(booker.BookPath, s)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go s.srv.Serve(ln)
+	return "http://" + ln.Addr().String() + booker.BookPath + "?LAB_JSON=1", nil
+}
+
+// Close stops a server started with Start.
+func (s *Server) Close() error {
+	if s.srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
+
+// ServeHTTP handles one booking request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	if err := r.ParseForm(); err != nil {
+		reply(w, "1", MsgBadRequest, "")
+		return
+	}
+	if r.Header.Get("api-token") != apiToken(r.PostForm.Get("api_time")) {
+		reply(w, "1", MsgBadToken, "")
+		return
+	}
+	req, err := parseRequest(r)
+	if err != nil {
+		reply(w, "1", MsgBadRequest+": "+err.Error(), "")
+		return
+	}
+	req.Received = now
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	switch {
+	case now.Before(s.OpenAt):
+		reply(w, "1", MsgNotOpen, "")
+	case s.bookings[req.UserID] != 0:
+		reply(w, "1", MsgAlreadyBooked, "")
+	case s.taken[req.SeatID]:
+		reply(w, "1", MsgSeatTaken, "")
+	default:
+		s.taken[req.SeatID] = true
+		s.bookings[req.UserID] = req.SeatID
+		reply(w, "ok", "预约成功", fmt.Sprintf("mock-%d", len(s.requests)))
+	}
+}
+
+// parseRequest reads the form fields sent by booker.BookSeat.
+func parseRequest(r *http.Request) (Request, error) {
+	seatID, err := strconv.Atoi(r.PostForm.Get("seats[0]"))
+	if err != nil || seatID <= 0 {
+		return Request{}, errors.New("seats[0]")
+	}
+	userID := r.PostForm.Get("seatBookers[0]")
+	if userID == "" {
+		return Request{}, errors.New("seatBookers[0]")
+	}
+	begin, err := strconv.ParseInt(r.PostForm.Get("beginTime"), 10, 64)
+	if err != nil {
+		return Request{}, errors.New("beginTime")
+	}
+	seconds, err := strconv.Atoi(r.PostForm.Get("duration"))
+	if err != nil || seconds <= 0 {
+		return Request{}, errors.New("duration")
+	}
+	return Request{
+		SeatID:    seatID,
+		UserID:    userID,
+		BeginTime: time.Unix(begin, 0),
+		Duration:  time.Duration(seconds) * time.Second,
+	}, nil
+}
+
+// apiToken mirrors the token the library expects for api_time.
+func apiToken(apiTime string) string {
+	hash := md5.Sum([]byte(apiTime))
+	return hex.EncodeToString(hash[:])
+}
+
+func reply(w http.ResponseWriter, code, message, bookingID string) {
+	resp := booker.BookResponseData{CODE: code, MESSAGE: message}
+	resp.DATA.BookingID = bookingID
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,104 @@
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"seat-killer/booker"
+)
+
+func TestServerFollowsBookingRules(t *testing.T) {
+	openAt := time.Date(2025, 3, 1, 20, 0, 0, 0, time.Local)
+	now := openAt.Add(-time.Second)
+	srv := New(openAt)
+	srv.Now = func() time.Time { return now }
+	srv.Take(102)
+	endpoint, err := srv.Start()
+	if err != nil {
+		t.Fatalf("启动 mock 服务失败: %v", err)
+	}
+	defer srv.Close()
+	booker.SetEndpoint(endpoint)
+	defer booker.SetEndpoint("")
+
+	book := func(userID string, seatID int) error {
+		_, err := booker.BookSeat(context.Background(), &booker.BookingRequest{
+			Client:    http.DefaultClient,
+			UserID:    userID,
+			SeatID:    seatID,
+			BeginTime: openAt.Add(48 * time.Hour),
+			Duration:  2 * time.Hour,
+		})
+		return err
+	}
+
+	if err := book("u1", 101); !errors.Is(err, booker.ErrNotOpen) {
+		t.Fatalf("开放前期望 ErrNotOpen, 实际为 %v", err)
+	}
+	now = openAt
+	testCases := []struct {
+		name   string
+		userID string
+		seatID int
+		want   error
+	}{
+		{"已被他人预约", "u1", 102, booker.ErrSeatTaken},
+		{"预约成功", "u1", 101, nil},
+		{"同一用户再次预约", "u1", 103, booker.ErrAlreadyBooked},
+		{"座位已被抢走", "u2", 101, booker.ErrSeatTaken},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := book(tc.userID, tc.seatID)
+			if tc.want == nil && err != nil {
+				t.Fatalf("期望成功, 实际为 %v", err)
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("期望 %v, 实际为 %v", tc.want, err)
+			}
+		})
+	}
+
+	if seatID, ok := srv.Booking("u1"); !ok || seatID != 101 {
+		t.Errorf("期望 u1 预约到 101, 实际为 %d (%t)", seatID, ok)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 5 {
+		t.Fatalf("期望记录 5 个请求, 实际为 %d", len(reqs))
+	}
+	if reqs[0].Duration != 2*time.Hour || !reqs[0].BeginTime.Equal(openAt.Add(48*time.Hour)) {
+		t.Errorf("请求参数解析错误: %+v", reqs[0])
+	}
+}
+
+func TestServerRejectsBadToken(t *testing.T) {
+	srv := New(time.Time{})
+	endpoint, err := srv.Start()
+	if err != nil {
+		t.Fatalf("启动 mock 服务失败: %v", err)
+	}
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, endpoint, nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("api-token", "wrong")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	var data booker.BookResponseData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		t.Fatalf("响应不是 JSON: %v", err)
+	}
+	if data.IsSuccess() || data.MESSAGE != MsgBadToken {
+		t.Errorf("期望 %q, 实际为 %+v", MsgBadToken, data)
+	}
+	if len(srv.Requests()) != 0 {
+		t.Error("token 错误的请求不应被记录")
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"seat-killer/config"
+	"seat-killer/mapper"
+	"seat-killer/redact"
+)
+
+// fastTestTask is the week_config entry reserved for the "test" command.
+const fastTestTask = "fast_test_task"
+
+// plannedSeat is a configured seat and the ID it resolves to; ID is 0 when unresolved.
+type plannedSeat struct {
+	Title string
+	ID    int
+}
+
+// dayPlan describes what the run on one day will do, or why it will do nothing.
+type dayPlan struct {
+	Date    time.Time
+	Weekday string
+	// Skipped explains why no booking happens on this day. The fields below are unset
+	// unless the day is skipped only because its window has passed.
+	Skipped string
+
+	Room        string
+	LoginAt     time.Time
+	PreemptAt   time.Time
+	OfficialAt  time.Time
+	FallbackEnd time.Time
+	TargetBegin time.Time
+	Durations   []time.Duration
+	Seats       []plannedSeat
+	Warnings    []string
+}
+
+// buildPlan lays out the runs of the next days, starting with today, as seen from
+// the local clock. The returned warnings concern the configuration as a whole.
+func buildPlan(seatCfg *config.SeatConfig, now time.Time, days int) ([]dayPlan, []string) {
+	var warnings []string
+	known := make(map[string]bool, len(weekdayNames))
+	for _, name := range weekdayNames {
+		known[name] = true
+	}
+	var keys []string
+	for key := range seatCfg.WeekConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] && key != fastTestTask {
+			warnings = append(warnings, fmt.Sprintf("week_config key %q is not a weekday (周一 ... 周日) and is never run", key))
+		}
+	}
+
+	preempt := time.Duration(seatCfg.Global.PreemptSeconds) * time.Second
+	prewarm := time.Duration(seatCfg.Global.PrewarmMinutes) * time.Minute
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	plans := make([]dayPlan, 0, days)
+	for offset := 0; offset < days; offset++ {
+		date := today.AddDate(0, 0, offset)
+		plan := dayPlan{Date: date, Weekday: weekdayNames[date.Weekday()]}
+		dayCfg, ok := seatCfg.WeekConfig[plan.Weekday]
+		switch {
+		case !ok:
+			plan.Skipped = "no week_config entry for " + plan.Weekday
+		case !dayCfg.Enable:
+			plan.Skipped = "disabled"
+		case len(dayCfg.Seats) == 0:
+			plan.Skipped = "enabled but no seats configured"
+		}
+		if plan.Skipped != "" {
+			plans = append(plans, plan)
+			continue
+		}
+
+		plan.Room = dayCfg.Name
+		plan.OfficialAt = time.Date(date.Year(), date.Month(), date.Day(), dayCfg.RunAtHour, dayCfg.RunAtMinute, 0, 0, date.Location())
+		plan.PreemptAt = plan.OfficialAt.Add(-preempt)
+		plan.LoginAt = plan.PreemptAt.Add(-prewarm)
+		plan.FallbackEnd = plan.OfficialAt.Add(fallbackWindow)
+		plan.TargetBegin = dayCfg.BeginTime(date.AddDate(0, 0, 2))
+		plan.Durations = dayCfg.DurationCandidates()
+
+		switch {
+		case now.After(plan.FallbackEnd):
+			plan.Skipped = "booking window already passed at " + plan.FallbackEnd.Format("15:04:05")
+		case now.After(plan.LoginAt):
+			plan.Warnings = append(plan.Warnings, "the run should already have started; a run started now gets a shortened pre-warm")
+		}
+		if plan.LoginAt.Before(date) {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("login time %s falls on the previous day; a run started then would pick the previous day's task", plan.LoginAt.Format("01-02 15:04:05")))
+		}
+		seen := make(map[string]bool, len(dayCfg.Seats))
+		for _, title := range dayCfg.Seats {
+			if seen[title] {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("seat %q is listed more than once", title))
+				continue
+			}
+			seen[title] = true
+			seatID, err := mapper.GetSeatID(dayCfg.Name, title)
+			if err != nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("seat %q cannot be resolved: %v", title, err))
+			}
+			plan.Seats = append(plan.Seats, plannedSeat{Title: title, ID: seatID})
+		}
+		plans = append(plans, plan)
+	}
+
+	// Runs are single-task processes: a window that reaches into the next one means
+	// the later task starts while the earlier run is still going.
+	for i := range plans {
+		for j := i + 1; j < len(plans); j++ {
+			a, b := &plans[i], &plans[j]
+			if a.LoginAt.IsZero() || b.LoginAt.IsZero() {
+				continue
+			}
+			if a.LoginAt.Before(b.FallbackEnd) && b.LoginAt.Before(a.FallbackEnd) {
+				msg := fmt.Sprintf("run windows of %s (%s) and %s (%s) overlap", a.Date.Format(time.DateOnly), a.Weekday, b.Date.Format(time.DateOnly), b.Weekday)
+				a.Warnings = append(a.Warnings, msg)
+				b.Warnings = append(b.Warnings, msg)
+			}
+		}
+	}
+	return plans, warnings
+}
+
+// printPlan writes the plan in a human-readable form.
+func printPlan(w io.Writer, plans []dayPlan, warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, plan := range plans {
+		header := fmt.Sprintf("%s %s", plan.Date.Format(time.DateOnly), plan.Weekday)
+		if plan.Skipped != "" {
+			fmt.Fprintf(w, "%s  nothing to do: %s\n", header, plan.Skipped)
+		} else {
+			fmt.Fprintf(w, "%s  room %s\n", header, plan.Room)
+			fmt.Fprintf(w, "    login      %s\n", plan.LoginAt.Format("15:04:05"))
+			fmt.Fprintf(w, "    preempt    %s\n", plan.PreemptAt.Format("15:04:05"))
+			fmt.Fprintf(w, "    official   %s (fallback until %s)\n", plan.OfficialAt.Format("15:04:05"), plan.FallbackEnd.Format("15:04:05"))
+			fmt.Fprintf(w, "    target     %s %s-%s (%s)\n", plan.TargetBegin.Format(time.DateOnly), plan.TargetBegin.Format("15:04"),
+				plan.TargetBegin.Add(plan.Durations[0]).Format("15:04"), formatDurations(plan.Durations))
+			fmt.Fprintf(w, "    seats      %s\n", formatSeats(plan.Seats))
+		}
+		for _, warning := range plan.Warnings {
+			fmt.Fprintf(w, "    warning: %s\n", warning)
+		}
+	}
+}
+
+func formatDurations(durations []time.Duration) string {
+	parts := make([]string, len(durations))
+	for i, d := range durations {
+		parts[i] = config.Duration(d).String()
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[0] + ", then " + strings.Join(parts[1:], ", ")
+}
+
+func formatSeats(seats []plannedSeat) string {
+	parts := make([]string, len(seats))
+	for i, seat := range seats {
+		if seat.ID == 0 {
+			parts[i] = seat.Title + " (unresolved)"
+		} else {
+			parts[i] = fmt.Sprintf("%s (#%d)", seat.Title, seat.ID)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// plan implements "seat-killer plan": it explains what the next runs will do
+// without logging in. It returns the process exit code.
+func (c *cli) plan(args []string) int {
+	stdout, stderr := c.stdout, c.stderr
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	days := fs.Int("days", 7, "number of days to show, starting with today")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *days <= 0 {
+		fmt.Fprintln(stderr, "-days must be positive")
+		return 2
+	}
+
+	// Only the school ID is shown, so the password reference is not resolved.
+	userInfo, err := config.ReadUserInfo(c.paths.userInfo)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load %s: %v\n", c.paths.userInfo, err)
+		return 1
+	}
+	seatCfg, err := config.LoadSeatConfig(c.paths.seatConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load %s: %v\n", c.paths.seatConfig, err)
+		return 1
+	}
+	redact.Default.SetMode(redact.Mode(seatCfg.Global.Redaction.SchoolID))
+	var warnings []string
+	if _, err := mapper.LoadSeatMap(c.paths.seatMap); err != nil {
+		warnings = append(warnings, fmt.Sprintf("seat map not loaded, seats cannot be resolved: %v", err))
+	}
+
+	now := time.Now()
+	plans, configWarnings := buildPlan(seatCfg, now, *days)
+	fmt.Fprintf(stdout, "Plan for account %s, %d days from %s (local clock)\n\n", redact.String(userInfo.SchoolID), *days, now.Format("2006-01-02 15:04"))
+	printPlan(stdout, plans, append(warnings, configWarnings...))
+	return 0
+}
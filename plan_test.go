@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/config"
+	"seat-killer/mapper"
+)
+
+func TestBuildPlan(t *testing.T) {
+	mapPath := filepath.Join(t.TempDir(), "seat_report.txt")
+	if err := os.WriteFile(mapPath, []byte("# Room: 一楼\nSeatID: 101, Title: 1\nSeatID: 102, Title: 2\n"), 0o600); err != nil {
+		t.Fatalf("写入座位表失败: %v", err)
+	}
+	if _, err := mapper.LoadSeatMap(mapPath); err != nil {
+		t.Fatalf("加载座位表失败: %v", err)
+	}
+
+	task := config.DayConfig{Enable: true, RunAtHour: 20, Name: "一楼", Seats: []string{"1", "2"}, BookStart: 8 * 60, Duration: config.Duration(4 * time.Hour)}
+	broken := task
+	broken.Seats = []string{"1", "9", "1"}
+	late := task
+	late.RunAtHour = 24 // opens at midnight, i.e. during the next day's window
+	early := task
+	early.RunAtHour, early.RunAtMinute = 0, 1
+	seatCfg := &config.SeatConfig{
+		Global: config.GlobalConfig{PreemptSeconds: 60, PrewarmMinutes: 2},
+		WeekConfig: map[string]config.DayConfig{
+			"周一":  task,
+			"周二":  {Enable: false},
+			"周三":  broken,
+			"周四":  late,
+			"周五":  early,
+			"周六":  {Enable: true},
+			"星期六": task,
+		},
+	}
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+
+	plans, warnings := buildPlan(seatCfg, monday.Add(21*time.Hour), 7)
+	if len(plans) != 7 {
+		t.Fatalf("期望 7 天的计划, 实际为 %d", len(plans))
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "星期六") {
+		t.Errorf("期望提示无效的 week_config 键, 实际为 %v", warnings)
+	}
+
+	testCases := []struct {
+		name        string
+		plan        dayPlan
+		wantSkipped string
+		wantWarning string
+	}{
+		{"窗口已过", plans[0], "already passed", ""},
+		{"未启用", plans[1], "disabled", ""},
+		{"座位无法解析", plans[2], "", "cannot be resolved"},
+		{"座位重复", plans[2], "", "more than once"},
+		{"午夜开放与次日窗口重叠", plans[3], "", "overlap"},
+		{"次日窗口与前一天重叠", plans[4], "", "overlap"},
+		{"登录时间落在前一天", plans[4], "", "previous day"},
+		{"未配置座位", plans[5], "no seats", ""},
+		{"缺少当天配置", plans[6], "no week_config entry", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(tc.plan.Skipped, tc.wantSkipped) || (tc.wantSkipped == "") != (tc.plan.Skipped == "") {
+				t.Errorf("期望跳过原因包含 %q, 实际为 %q", tc.wantSkipped, tc.plan.Skipped)
+			}
+			if tc.wantWarning != "" && !strings.Contains(strings.Join(tc.plan.Warnings, "\n"), tc.wantWarning) {
+				t.Errorf("期望警告包含 %q, 实际为 %v", tc.wantWarning, tc.plan.Warnings)
+			}
+		})
+	}
+
+	wednesday := plans[2]
+	if want := monday.AddDate(0, 0, 2).Add(20*time.Hour - 3*time.Minute); !wednesday.LoginAt.Equal(want) {
+		t.Errorf("期望登录时间 %s, 实际为 %s", want, wednesday.LoginAt)
+	}
+	if want := monday.AddDate(0, 0, 4).Add(8 * time.Hour); !wednesday.TargetBegin.Equal(want) {
+		t.Errorf("期望目标时间 %s, 实际为 %s", want, wednesday.TargetBegin)
+	}
+	if len(wednesday.Seats) != 2 || wednesday.Seats[0].ID != 101 || wednesday.Seats[1].ID != 0 {
+		t.Errorf("座位解析错误: %+v", wednesday.Seats)
+	}
+
+	var out bytes.Buffer
+	printPlan(&out, plans, warnings)
+	for _, want := range []string{"2026-10-21 周三  room 一楼", "1 (#101), 9 (unresolved)", "2026-10-23 08:00-12:00 (4h)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("输出中缺少 %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPlanDoesNotResolvePasswordRef(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		seatConfigFile: serveTestConfig,
+		userInfoFile:   "school_id: \"23000001\"\npassword_ref: \"env:SEAT_KILLER_TEST_UNSET_PASSWORD\"\n",
+		seatMapFile:    "# Room: 一楼\nSeatID: 101, Title: 35\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"--config-dir", dir, "plan", "-days", "1"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("期望退出码 0, 实际为 %d:\n%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Plan for account") {
+		t.Errorf("期望输出计划:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"seat-killer/booker"
+	"seat-killer/breaker"
+	"seat-killer/retry"
+)
+
+// Retry policies for the operations of a run, each tuned to how that endpoint fails.
+var (
+	// credentialPolicy runs long before the window, so it can afford to back off gently.
+	credentialPolicy = retry.Policy{
+		Name:         "validate",
+		Observer:     countRetries("validate"),
+		MaxAttempts:  3,
+		Backoff:      retry.Exponential,
+		InitialDelay: 2 * time.Second,
+		Jitter:       true,
+	}
+	// loginPolicy rides out SSO hiccups for about a minute. Decorrelated jitter keeps
+	// several accounts from hammering the SSO in lockstep.
+	loginPolicy = retry.Policy{
+		Name:         "login",
+		Observer:     countRetries("login"),
+		MaxAttempts:  20,
+		MaxElapsed:   time.Minute,
+		Backoff:      retry.DecorrelatedJitter,
+		InitialDelay: time.Second,
+		MaxDelay:     5 * time.Second,
+	}
+	// userInfoPolicy covers the single user info lookup right after login.
+	userInfoPolicy = retry.Policy{
+		Name:         "user-info",
+		Observer:     countRetries("user-info"),
+		MaxAttempts:  3,
+		Backoff:      retry.Exponential,
+		InitialDelay: 500 * time.Millisecond,
+	}
+	// bookingPolicy retries transport failures once, quickly, and a refusal whose
+	// Retry-After header says when to come back once, after that delay (at most a
+	// second, as the attempt holds one of the in-flight slots meanwhile). Other
+	// server refusals and requests rejected by an open circuit breaker are not
+	// retried here; the engine decides what to do with them in a later round.
+	bookingPolicy = retry.Policy{
+		Name:         "book",
+		Observer:     countRetries("book"),
+		MaxAttempts:  2,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Retryable:    isBookingRetryable,
+	}
+)
+
+// isBookingRetryable reports whether err is a network-level failure or a refusal
+// carrying the server's Retry-After hint, rather than any other server refusal or
+// a request the circuit breaker did not send.
+func isBookingRetryable(err error) bool {
+	var open *breaker.OpenError
+	if errors.As(err, &open) {
+		return false
+	}
+	var hint retry.RetryAfterError
+	if errors.As(err, &hint) {
+		return true
+	}
+	var refusal *booker.BookingError
+	return !errors.As(err, &refusal)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/booker"
+	"seat-killer/breaker"
+	"seat-killer/retry"
+)
+
+func TestIsBookingRetryable(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"网络错误", &url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("connection reset")}, true},
+		{"服务器拒绝", booker.ErrSeatTaken, false},
+		{"熔断器拒绝", &url.Error{Op: "Post", URL: "https://example.com", Err: &breaker.OpenError{Host: "example.com"}}, false},
+		{"服务器给出 Retry-After", retry.WithRetryAfter(booker.ErrTooFrequent, time.Second), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isBookingRetryable(tc.err); got != tc.want {
+				t.Errorf("isBookingRetryable(%v) 期望 %v, 实际为 %v", tc.err, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBookingPolicyWaitsForRetryAfter(t *testing.T) {
+	requests := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		if requests == 1 {
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}},
+				Body: io.NopCloser(strings.NewReader(`{"CODE":"1","MESSAGE":"操作过于频繁"}`)), Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header),
+			Body: io.NopCloser(strings.NewReader(`{"CODE":"ok","MESSAGE":"预约成功"}`)), Request: req}, nil
+	})}
+	policy := bookingPolicy
+	var delays []time.Duration
+	policy.Observer = func(a retry.Attempt) { delays = append(delays, a.Delay) }
+
+	err := policy.Do(context.Background(), func() error {
+		_, err := booker.BookSeat(context.Background(), &booker.BookingRequest{Client: client, UserID: "42", SeatID: 1, Duration: time.Hour})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("期望按 Retry-After 等待后重试成功, 实际为 %v", err)
+	}
+	if requests != 2 || len(delays) != 2 || delays[0] != time.Second {
+		t.Errorf("期望等待 Retry-After 给出的 1s 后重试一次, 实际请求 %d 次、等待 %v", requests, delays)
+	}
+}
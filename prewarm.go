@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"seat-killer/logging"
+	"seat-killer/retry"
+	"seat-killer/user"
+)
+
+// revalidateLead is how long before the preempt time the session is checked one last time.
+const revalidateLead = 5 * time.Second
+
+// warmSession is the part of *sso.Session that keepWarm uses.
+type warmSession interface {
+	Client() *http.Client
+	Login(ctx context.Context) error
+}
+
+// keepWarm holds a freshly logged-in session until preemptTime. Every interval it
+// fetches the user info, which both proves the session is still valid and keeps the
+// TLS connection to the library host open. A final check runs revalidateLead before
+// preemptTime so the first booking request goes out on a verified, hot connection.
+// A session found invalid is replaced with a fresh login, outside the re-login budget
+// reserved for the booking window.
+func keepWarm(ctx context.Context, session warmSession, preemptTime time.Time, interval time.Duration) {
+	logger := logging.From(ctx)
+	revalidateAt := preemptTime.Add(-revalidateLead)
+	if !time.Now().Before(revalidateAt) {
+		return // Just logged in and verified; nothing left to warm.
+	}
+	for {
+		next := time.Now().Add(interval)
+		if !next.Before(revalidateAt) {
+			break
+		}
+		if retry.Sleep(ctx, time.Until(next)) != nil {
+			return
+		}
+		if _, err := user.GetUserInfo(ctx, session.Client()); err != nil {
+			logger.Warn("Keep-alive check failed, logging in again", logging.Err(err))
+			if err := session.Login(ctx); err != nil {
+				logger.Error("Re-login during pre-warm failed", logging.Err(err))
+			}
+		}
+	}
+
+	if retry.Sleep(ctx, time.Until(revalidateAt)) != nil {
+		return
+	}
+	if _, err := user.GetUserInfo(ctx, session.Client()); err != nil {
+		logger.Warn("Final session check failed, logging in again", logging.Err(err))
+		if err := session.Login(ctx); err != nil {
+			logger.Error("Re-login before preempt time failed", logging.Err(err))
+			return
+		}
+		if _, err := user.GetUserInfo(ctx, session.Client()); err != nil {
+			logger.Error("Session still invalid", logging.Err(err))
+			return
+		}
+	}
+	logger.Info("Session verified, connection is warm")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWarmSession answers user info checks from an in-memory session that can be
+// made to expire, and records when each check was made.
+type fakeWarmSession struct {
+	// expireCheck expires the session right before that keep-alive check (1-based); 0 never.
+	expireCheck int
+	// expireAfter expires the session once, on the first check at or after this time.
+	expireAfter time.Time
+	loginErr    error
+
+	mu      sync.Mutex
+	valid   bool
+	expired bool
+	checks  []time.Time
+	logins  int
+}
+
+func (s *fakeWarmSession) Client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := time.Now()
+		s.checks = append(s.checks, now)
+		if len(s.checks) == s.expireCheck || (!s.expireAfter.IsZero() && !now.Before(s.expireAfter) && !s.expired) {
+			s.valid, s.expired = false, true
+		}
+		body := `{"DATA":{}}`
+		if s.valid {
+			body = `{"DATA":{"uid":"42"}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), Request: req}, nil
+	})}
+}
+
+func (s *fakeWarmSession) Login(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logins++
+	if s.loginErr != nil {
+		return s.loginErr
+	}
+	s.valid = true
+	return nil
+}
+
+func TestKeepWarm(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	testCases := []struct {
+		name        string
+		expireCheck int
+		expireFinal bool
+		loginErr    error
+		wantLogins  int
+		// wantFinal is the number of checks made at or after the re-validation time.
+		wantFinal int
+	}{
+		{name: "会话一直有效", wantFinal: 1},
+		{name: "保活检查失败时重新登录", expireCheck: 2, wantLogins: 1, wantFinal: 1},
+		{name: "最后一次检查失败时重新登录并再次验证", expireFinal: true, wantLogins: 1, wantFinal: 2},
+		{name: "最后一次检查失败且重新登录失败", expireFinal: true, loginErr: errors.New("cas unavailable"), wantLogins: 1, wantFinal: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			preemptTime := time.Now().Add(revalidateLead + 450*time.Millisecond)
+			revalidateAt := preemptTime.Add(-revalidateLead)
+			session := &fakeWarmSession{valid: true, expireCheck: tc.expireCheck, loginErr: tc.loginErr}
+			if tc.expireFinal {
+				session.expireAfter = revalidateAt
+			}
+
+			keepWarm(context.Background(), session, preemptTime, interval)
+
+			if session.logins != tc.wantLogins {
+				t.Errorf("期望重新登录 %d 次, 实际为 %d 次", tc.wantLogins, session.logins)
+			}
+			var keepAlive, final int
+			for _, at := range session.checks {
+				if at.Before(revalidateAt) {
+					keepAlive++
+				} else {
+					final++
+				}
+			}
+			if keepAlive < 2 {
+				t.Errorf("期望每 %s 保活一次, 实际只检查了 %d 次", interval, keepAlive)
+			}
+			if final != tc.wantFinal {
+				t.Errorf("期望在 %s 后检查 %d 次, 实际为 %d 次", revalidateAt.Format("15:04:05.000"), tc.wantFinal, final)
+			}
+			if last := session.checks[len(session.checks)-1]; !last.Before(preemptTime) {
+				t.Errorf("最后一次检查应早于抢座开始时间, 实际为 %s", last.Format("15:04:05.000"))
+			}
+		})
+	}
+}
+
+func TestKeepWarmReturnsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	session := &fakeWarmSession{valid: true}
+	start := time.Now()
+	keepWarm(ctx, session, start.Add(time.Minute), 100*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second || len(session.checks) != 0 {
+		t.Errorf("取消后应立即返回且不再检查, 实际耗时 %s、检查 %d 次", elapsed, len(session.checks))
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package ratelimit
+
+import (
+	"errors"
+	"os"
+)
+
+// Without flock the shared budget is unavailable and every limiter paces its own process.
+func lockFile(*os.File) error {
+	return errors.ErrUnsupported
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"seat-killer/logging"
+)
+
+// Config describes a token bucket with adaptive backoff.
+type Config struct {
+	// Rate is the starting and maximum number of requests per second.
+	Rate float64
+	// Burst is the bucket size, i.e. how many requests may go out back to back.
+	Burst int
+	// MinRate is the floor the rate never backs off below.
+	MinRate float64
+	// BackoffFactor multiplies the rate each time the server throttles us (0 < f < 1).
+	BackoffFactor float64
+	// RecoveryPerSecond is how much rate is regained per second after a backoff.
+	RecoveryPerSecond float64
+}
+
+// bucket is the state of a token bucket, kept in memory or in a Store.
+type bucket struct {
+	Rate   float64   `json:"rate"`
+	Tokens float64   `json:"tokens"`
+	Last   time.Time `json:"last"`
+}
+
+// Limiter is a token bucket whose rate backs off multiplicatively when the server
+// reports throttling and recovers additively afterwards. A limiter created with
+// NewShared keeps its bucket in a Store, so that every process using the same
+// store, e.g. one run per account booking from the same IP, shares one budget.
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu sync.Mutex
+	// store holds the shared bucket; nil when the budget is this process's own.
+	store *Store
+	local bucket
+}
+
+// New creates a limiter starting at cfg.Rate with a full bucket.
+func New(cfg Config) *Limiter {
+	return newWithClock(cfg, nil, time.Now)
+}
+
+// NewShared creates a limiter whose bucket lives in store. A store that has not
+// been used yet starts at cfg.Rate with a full bucket. When the store cannot be
+// used, the limiter logs a warning and paces this process only.
+func NewShared(cfg Config, store *Store) *Limiter {
+	return newWithClock(cfg, store, time.Now)
+}
+
+func newWithClock(cfg Config, store *Store, now func() time.Time) *Limiter {
+	return &Limiter{cfg: cfg, now: now, store: store, local: bucket{Rate: cfg.Rate, Tokens: float64(cfg.Burst), Last: now()}}
+}
+
+// update runs fn on the bucket after refilling it, holding mu and, for a shared
+// bucket, the store's lock.
+func (l *Limiter) update(fn func(b *bucket)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.store != nil {
+		err := l.store.update(func(b *bucket, found bool) {
+			if !found {
+				*b = bucket{Rate: l.cfg.Rate, Tokens: float64(l.cfg.Burst), Last: l.now()}
+			}
+			l.advance(b)
+			fn(b)
+		})
+		if err == nil {
+			return
+		}
+		slog.Warn("Shared rate limit unavailable, pacing this process only", "path", l.store.Path, logging.Err(err))
+		l.store = nil
+	}
+	l.advance(&l.local)
+	fn(&l.local)
+}
+
+// advance refills the bucket and recovers the rate for the time elapsed.
+func (l *Limiter) advance(b *bucket) {
+	// Another process may have backed the rate off below our floor or run with a
+	// larger bucket; our own settings bound what we take from it.
+	b.Rate = max(b.Rate, l.cfg.MinRate)
+	now := l.now()
+	elapsed := now.Sub(b.Last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.Last = now
+	if b.Rate < l.cfg.Rate {
+		b.Rate = min(l.cfg.Rate, b.Rate+l.cfg.RecoveryPerSecond*elapsed)
+	}
+	b.Tokens = min(float64(l.cfg.Burst), b.Tokens+b.Rate*elapsed)
+}
+
+// reserve takes a token, possibly going into debt, and returns how long the
+// caller must wait before the token is actually available.
+func (l *Limiter) reserve() time.Duration {
+	var wait time.Duration
+	l.update(func(b *bucket) {
+		b.Tokens--
+		if b.Tokens < 0 {
+			wait = time.Duration(-b.Tokens / b.Rate * float64(time.Second))
+		}
+	})
+	return wait
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	wait := l.reserve()
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Backoff reacts to a throttling response: the rate is cut by BackoffFactor
+// (down to MinRate) and the bucket is emptied so the next request waits.
+func (l *Limiter) Backoff() {
+	var rate float64
+	l.update(func(b *bucket) {
+		b.Rate = max(l.cfg.MinRate, b.Rate*l.cfg.BackoffFactor)
+		b.Tokens = min(b.Tokens, 0)
+		rate = b.Rate
+	})
+	slog.Warn("Rate limiter backing off", "rate", rate)
+}
+
+// Rate returns the current rate in requests per second.
+func (l *Limiter) Rate() float64 {
+	var rate float64
+	l.update(func(b *bucket) { rate = b.Rate })
+	return rate
+}
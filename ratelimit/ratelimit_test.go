@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestBackoffAndRecovery(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC)}
+	l := newWithClock(Config{Rate: 4, Burst: 1, MinRate: 1, BackoffFactor: 0.5, RecoveryPerSecond: 0.5}, nil, clock.now)
+
+	if wait := l.reserve(); wait != 0 {
+		t.Fatalf("期望首个令牌立即可用, 实际需等待 %s", wait)
+	}
+	if wait := l.reserve(); wait != 250*time.Millisecond {
+		t.Fatalf("期望 4 req/s 时等待 250ms, 实际为 %s", wait)
+	}
+
+	l.Backoff()
+	l.Backoff()
+	l.Backoff()
+	if got := l.Rate(); got != 1 {
+		t.Fatalf("期望速率退避到下限 1, 实际为 %.2f", got)
+	}
+
+	clock.advance(2 * time.Second)
+	if got := l.Rate(); got != 2 {
+		t.Fatalf("期望 2 秒后恢复到 2 req/s, 实际为 %.2f", got)
+	}
+	clock.advance(time.Minute)
+	if got := l.Rate(); got != 4 {
+		t.Fatalf("期望恢复不超过初始速率 4, 实际为 %.2f", got)
+	}
+}
+
+func TestSharedBudget(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC)}
+	cfg := Config{Rate: 4, Burst: 2, MinRate: 1, BackoffFactor: 0.5, RecoveryPerSecond: 0.5}
+	store := HostStore(filepath.Join(t.TempDir(), "ratelimit"), "hdu.huitu.zhishulib.com")
+	first := newWithClock(cfg, store, clock.now)
+	second := newWithClock(cfg, store, clock.now)
+
+	if wait := first.reserve(); wait != 0 {
+		t.Fatalf("期望首个令牌立即可用, 实际需等待 %s", wait)
+	}
+	if wait := second.reserve(); wait != 0 {
+		t.Fatalf("期望桶中第二个令牌立即可用, 实际需等待 %s", wait)
+	}
+	if wait := first.reserve(); wait != 250*time.Millisecond {
+		t.Fatalf("两个限速器应共享令牌桶, 期望等待 250ms, 实际为 %s", wait)
+	}
+	if wait := second.reserve(); wait != 500*time.Millisecond {
+		t.Fatalf("期望共享的欠账累积到 500ms, 实际为 %s", wait)
+	}
+
+	second.Backoff()
+	if got := first.Rate(); got != 2 {
+		t.Fatalf("一个限速器退避后另一个应看到 2 req/s, 实际为 %.2f", got)
+	}
+	if got := New(cfg).Rate(); got != 4 {
+		t.Fatalf("不共享的限速器不应受影响, 实际为 %.2f", got)
+	}
+}
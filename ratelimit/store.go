@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Store keeps a token bucket in a file, so that limiters in separate processes
+// draw from one budget. Every update holds an exclusive lock on the file.
+type Store struct {
+	Path string
+}
+
+// HostStore returns the store of the budget for requests to host, kept in dir.
+func HostStore(dir, host string) *Store {
+	return &Store{Path: filepath.Join(dir, host+".json")}
+}
+
+// update locks the file, hands fn the bucket it holds (found is false when the
+// file is new or unreadable) and writes the bucket back.
+func (s *Store) update(fn func(b *bucket, found bool)) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	var b bucket
+	found := len(data) > 0 && json.Unmarshal(data, &b) == nil
+	fn(&b, found)
+	out, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(out, 0)
+	return err
+}
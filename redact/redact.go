@@ -0,0 +1,196 @@
+// Package redact masks secrets in text before it reaches a log or an error message.
+//
+// Passwords, session cookies, CAS tickets and any registered secret are always
+// masked. School IDs are masked according to the configured Mode.
+package redact
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mode controls how school IDs are masked.
+type Mode string
+
+const (
+	// ModePartial keeps the first and last two characters, e.g. "23****01".
+	ModePartial Mode = "partial"
+	// ModeFull replaces the whole ID.
+	ModeFull Mode = "full"
+	// ModeOff leaves IDs readable.
+	ModeOff Mode = "off"
+)
+
+const mask = "****"
+
+// patterns catch secrets that were never registered, e.g. a cookie echoed in an error page.
+var patterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)((?:set-)?cookie:\s*)[^\r\n]+`), "${1}" + mask},
+	{regexp.MustCompile(`(?i)\b(PHPSESSID|JSESSIONID|SESSION|CASTGC|TGC)=[^;\s&"',]+`), "${1}=" + mask},
+	{regexp.MustCompile(`(?i)\b(password|passwd|croypto)=[^&\s"',]+`), "${1}=" + mask},
+	{regexp.MustCompile(`(?i)("(?:password|passwd)"\s*:\s*")[^"]*`), "${1}" + mask},
+	{regexp.MustCompile(`\bticket=ST-[^&\s"',]+`), "ticket=" + mask},
+}
+
+// Redactor masks registered secrets, school IDs and well-known secret patterns.
+// It is safe for concurrent use.
+type Redactor struct {
+	mu      sync.RWMutex
+	mode    Mode
+	secrets []string
+	ids     []string
+}
+
+// New returns a Redactor that masks school IDs according to mode.
+func New(mode Mode) *Redactor {
+	return &Redactor{mode: mode}
+}
+
+// Default is used by the package-level functions and the process-wide log writer.
+var Default = New(ModePartial)
+
+// SetMode changes how school IDs are masked.
+func (r *Redactor) SetMode(mode Mode) {
+	r.mu.Lock()
+	r.mode = mode
+	r.mu.Unlock()
+}
+
+// Mode returns how school IDs are masked.
+func (r *Redactor) Mode() Mode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mode
+}
+
+// AddSecret registers a value that must never appear, such as a password or a session ID.
+func (r *Redactor) AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	r.mu.Lock()
+	r.secrets = insertByLength(r.secrets, secret)
+	r.mu.Unlock()
+}
+
+// AddID registers a school ID to be masked according to the mode.
+func (r *Redactor) AddID(id string) {
+	if id == "" {
+		return
+	}
+	r.mu.Lock()
+	r.ids = insertByLength(r.ids, id)
+	r.mu.Unlock()
+}
+
+// insertByLength keeps values longest first, so a secret containing another is masked whole.
+func insertByLength(values []string, v string) []string {
+	for _, existing := range values {
+		if existing == v {
+			return values
+		}
+	}
+	values = append(values, v)
+	sort.SliceStable(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
+
+// String returns s with every secret masked.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, mask)
+	}
+	if r.mode != ModeOff {
+		for _, id := range r.ids {
+			s = strings.ReplaceAll(s, id, maskID(id, r.mode))
+		}
+	}
+	r.mu.RUnlock()
+	for _, p := range patterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+func maskID(id string, mode Mode) string {
+	runes := []rune(id)
+	if mode == ModeFull || len(runes) < 6 {
+		return mask
+	}
+	return string(runes[:2]) + mask + string(runes[len(runes)-2:])
+}
+
+// Preview returns at most n bytes of body, redacted, for error messages.
+func (r *Redactor) Preview(body []byte, n int) string {
+	s := string(body)
+	if len(s) > n {
+		s = strings.ToValidUTF8(s[:n], "") + "..."
+	}
+	return r.String(s)
+}
+
+// HandlerOptions returns slog handler options whose ReplaceAttr masks secrets in
+// the message and every attribute value before the handler formats them. The
+// text and JSON handlers escape quotes, backslashes and control characters, after
+// which a secret containing one no longer matches, so a Writer alone is not enough.
+func (r *Redactor) HandlerOptions(level slog.Leveler) *slog.HandlerOptions {
+	return &slog.HandlerOptions{Level: level, ReplaceAttr: r.replaceAttr}
+}
+
+func (r *Redactor) replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(r.String(a.Value.String()))
+	case slog.KindAny:
+		// Errors, stringers and the like: only replace the value when it held a
+		// secret, so that everything else keeps the handler's own formatting.
+		text := fmt.Sprint(a.Value.Any())
+		if redacted := r.String(text); redacted != text {
+			a.Value = slog.StringValue(redacted)
+		}
+	}
+	return a
+}
+
+// AddSecret registers a secret with Default.
+func AddSecret(secret string) { Default.AddSecret(secret) }
+
+// AddID registers a school ID with Default.
+func AddID(id string) { Default.AddID(id) }
+
+// String redacts s with Default.
+func String(s string) string { return Default.String(s) }
+
+// Preview returns a redacted, truncated preview of body using Default.
+func Preview(body []byte, n int) string { return Default.Preview(body, n) }
+
+// Writer redacts everything written through it. log writes one entry per call,
+// so a secret is never split across writes. A slog handler writing through it
+// also needs HandlerOptions, which redacts before values are escaped.
+type Writer struct {
+	out io.Writer
+	r   *Redactor
+}
+
+// NewWriter returns a Writer that redacts with r before writing to out.
+func NewWriter(out io.Writer, r *Redactor) *Writer {
+	return &Writer{out: out, r: r}
+}
+
+// Write implements io.Writer. It reports len(p) on success even though the
+// redacted text may have a different length.
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
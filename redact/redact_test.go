@@ -0,0 +1,79 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mode  Mode
+		input string
+		want  string
+	}{
+		{name: "注册的密码", mode: ModePartial, input: "login failed for hunter2!", want: "login failed for ****!"},
+		{name: "学号部分遮盖", mode: ModePartial, input: "SchoolID [23051234]", want: "SchoolID [23****34]"},
+		{name: "学号完全遮盖", mode: ModeFull, input: "SchoolID [23051234]", want: "SchoolID [****]"},
+		{name: "学号不遮盖", mode: ModeOff, input: "SchoolID [23051234]", want: "SchoolID [23051234]"},
+		{name: "PHPSESSID", mode: ModePartial, input: "Cookie PHPSESSID=abc123; path=/", want: "Cookie PHPSESSID=****; path=/"},
+		{name: "Cookie 请求头", mode: ModePartial, input: "Set-Cookie: CASTGC=TGT-1-xyz; Path=/\nnext", want: "Set-Cookie: ****\nnext"},
+		{name: "表单中的密码", mode: ModePartial, input: "username=a&password=c2VjcmV0&type=x", want: "username=a&password=****&type=x"},
+		{name: "JSON 中的密码", mode: ModePartial, input: `{"password":"s3cret","a":1}`, want: `{"password":"****","a":1}`},
+		{name: "CAS 票据", mode: ModePartial, input: "GET /login?ticket=ST-123-abc&x=1", want: "GET /login?ticket=****&x=1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New(tc.mode)
+			r.AddSecret("hunter2")
+			r.AddID("23051234")
+			if got := r.String(tc.input); got != tc.want {
+				t.Errorf("期望 %q, 实际为 %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLongestSecretFirst(t *testing.T) {
+	r := New(ModeFull)
+	r.AddSecret("abc")
+	r.AddSecret("abcdef")
+	if got := r.String("x abcdef y"); got != "x **** y" {
+		t.Errorf("期望整体遮盖较长的密钥, 实际为 %q", got)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	r := New(ModePartial)
+	body := []byte("PHPSESSID=abcdef " + strings.Repeat("x", 500))
+	got := r.Preview(body, 40)
+	if strings.Contains(got, "abcdef") || len(got) > 60 {
+		t.Errorf("预览未截断或未遮盖: %q", got)
+	}
+}
+
+func TestWriterKeepsSecretsOutOfLogs(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(ModePartial)
+	r.AddSecret("hunter2")
+	r.AddSecret("sess-value")
+	r.AddID("23051234")
+	logger := log.New(NewWriter(&buf, r), "", 0)
+
+	logger.Printf("Logged in as SchoolID [%s] with %s", "23051234", "hunter2")
+	logger.Printf("request failed: %v", fmt.Errorf("got cookie PHPSESSID=%s", "sess-value"))
+	logger.Println("POST body username=23051234&password=hunter2")
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "sess-value", "23051234"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("日志中出现了 %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "23****34") {
+		t.Errorf("期望保留部分学号以便排查:\n%s", out)
+	}
+}
@@ -0,0 +1,186 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"seat-killer/logging"
+)
+
+// Backoff selects how the delay grows between attempts.
+type Backoff int
+
+const (
+	// Constant waits InitialDelay between every attempt.
+	Constant Backoff = iota
+	// Exponential multiplies the delay by Multiplier after every attempt.
+	Exponential
+	// DecorrelatedJitter picks a random delay between InitialDelay and three times
+	// the previous delay, which spreads out clients that failed at the same moment.
+	DecorrelatedJitter
+)
+
+// RetryAfterError is implemented by errors that know when the operation may be retried,
+// e.g. from a Retry-After header. The hint replaces the computed delay, but is still
+// capped by Policy.MaxDelay.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string             { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error             { return e.err }
+func (e *retryAfterError) RetryAfter() time.Duration { return e.after }
+
+// WithRetryAfter attaches a retry hint to err.
+func WithRetryAfter(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, after: after}
+}
+
+// Attempt describes one finished attempt, as reported to Policy.Observer.
+type Attempt struct {
+	// Number starts at 1.
+	Number int
+	Err    error
+	// Elapsed is the time since the first attempt started.
+	Elapsed time.Duration
+	// Delay is the wait before the next attempt; zero when this was the last one.
+	Delay time.Duration
+	// Final is set on the last attempt, whether it succeeded or not.
+	Final bool
+}
+
+// Policy describes how an operation is retried. The zero value makes a single attempt.
+type Policy struct {
+	// Name identifies the operation in logs and observer callbacks.
+	Name string
+	// MaxAttempts bounds the number of attempts; 0 or 1 means no retries.
+	MaxAttempts int
+	// MaxElapsed, when set, stops retrying once the next attempt would start later than this.
+	MaxElapsed time.Duration
+	Backoff    Backoff
+	// InitialDelay is the first delay, and the lower bound for DecorrelatedJitter.
+	InitialDelay time.Duration
+	// MaxDelay caps every delay when set.
+	MaxDelay time.Duration
+	// Multiplier grows Exponential delays. Defaults to 2.
+	Multiplier float64
+	// Jitter randomises Exponential delays over [0, delay] ("full jitter").
+	Jitter bool
+	// Retryable decides whether an error is worth another attempt. By default
+	// everything except UnretryableError and context errors is retried.
+	Retryable func(error) bool
+	// Observer, when set, is called after every attempt, e.g. to record metrics.
+	Observer func(Attempt)
+}
+
+// Do runs fn until it succeeds, the policy gives up, or ctx is done. An
+// UnretryableError is unwrapped before being returned.
+func (p Policy) Do(ctx context.Context, fn Func) error {
+	maxAttempts := max(p.MaxAttempts, 1)
+	start := time.Now()
+	var base, delay time.Duration
+	var err error
+	for i := 1; i <= maxAttempts; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err == nil {
+				err = ctxErr
+			}
+			return err
+		}
+		err = fn()
+		attempt := Attempt{Number: i, Err: err, Elapsed: time.Since(start)}
+		if err == nil {
+			attempt.Final = true
+			p.observe(attempt)
+			return nil // Success
+		}
+
+		if !p.retryable(err) {
+			attempt.Final = true
+			p.observe(attempt)
+			logging.From(ctx).Warn("Attempt failed with unretryable error", "policy", p.Name, logging.Attempt(i), "max_attempts", maxAttempts, logging.Err(err))
+			var unretryableErr *UnretryableError
+			if errors.As(err, &unretryableErr) {
+				return unretryableErr.Unwrap() // Not retryable, return original error
+			}
+			return err
+		}
+
+		base, delay = p.nextDelay(base)
+		var hint RetryAfterError
+		if errors.As(err, &hint) {
+			delay = hint.RetryAfter()
+			if p.MaxDelay > 0 {
+				delay = min(delay, p.MaxDelay)
+			}
+		}
+		if i == maxAttempts || (p.MaxElapsed > 0 && time.Since(start)+delay > p.MaxElapsed) {
+			attempt.Final = true
+			p.observe(attempt)
+			break
+		}
+		attempt.Delay = delay
+		p.observe(attempt)
+		logging.From(ctx).Warn("Attempt failed, retrying", "policy", p.Name, logging.Attempt(i), "max_attempts", maxAttempts, logging.Err(err), "delay_ms", delay.Milliseconds())
+		if Sleep(ctx, delay) != nil {
+			break // Cancelled while waiting; report the last real error.
+		}
+	}
+	return err // Return the last error
+}
+
+func (p Policy) retryable(err error) bool {
+	var unretryableErr *UnretryableError
+	if errors.As(err, &unretryableErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return true
+}
+
+// nextDelay returns the next un-jittered delay, which the following call grows
+// from, and the delay to actually wait. prevBase is zero before the first retry.
+func (p Policy) nextDelay(prevBase time.Duration) (base, wait time.Duration) {
+	switch p.Backoff {
+	case Exponential:
+		base = p.InitialDelay
+		if prevBase > 0 {
+			multiplier := p.Multiplier
+			if multiplier <= 0 {
+				multiplier = 2
+			}
+			base = time.Duration(float64(prevBase) * multiplier)
+		}
+	case DecorrelatedJitter:
+		upper := max(prevBase*3, p.InitialDelay)
+		base = p.InitialDelay + rand.N(upper-p.InitialDelay+1)
+	default:
+		base = p.InitialDelay
+	}
+	if p.MaxDelay > 0 {
+		base = min(base, p.MaxDelay)
+	}
+	if p.Jitter && p.Backoff == Exponential && base > 0 {
+		return base, rand.N(base + 1)
+	}
+	return base, base
+}
+
+func (p Policy) observe(a Attempt) {
+	if p.Observer != nil {
+		p.Observer(a)
+	}
+}
@@ -1,8 +1,7 @@
 package retry
 
 import (
-	"errors"
-	"log"
+	"context"
 	"time"
 )
 
@@ -32,24 +31,25 @@ func WrapUnretryable(err error) error {
 	return &UnretryableError{Err: err}
 }
 
-// WithRetry executes a function with a specified number of retry attempts and delay.
-// It stops retrying if the function returns an UnretryableError.
-func WithRetry(fn Func, attempts int, delay time.Duration) error {
-	var err error
-	for i := 0; i < attempts; i++ {
-		err = fn()
-		if err == nil {
-			return nil // Success
-		}
-
-		var unretryableErr *UnretryableError
-		if errors.As(err, &unretryableErr) {
-			log.Printf("Attempt %d/%d failed with unretryable error: %v", i+1, attempts, err)
-			return unretryableErr.Unwrap() // Not retryable, return original error
-		}
-
-		log.Printf("Attempt %d/%d failed: %v. Retrying in %s...", i+1, attempts, err, delay)
-		time.Sleep(delay)
+// Sleep pauses for d or until ctx is done, whichever comes first.
+// It returns ctx.Err() if the pause was cut short.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
 	}
-	return err // Return the last error
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WithRetry executes a function with a specified number of retry attempts and a constant delay.
+// It stops retrying if the function returns an UnretryableError or ctx is done.
+// Use Policy for backoff, jitter and classification.
+func WithRetry(ctx context.Context, fn Func, attempts int, delay time.Duration) error {
+	return Policy{MaxAttempts: attempts, InitialDelay: delay}.Do(ctx, fn)
 }
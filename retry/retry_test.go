@@ -0,0 +1,112 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestPolicyExponentialDelays(t *testing.T) {
+	var delays []time.Duration
+	p := Policy{
+		MaxAttempts:  4,
+		Backoff:      Exponential,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     3 * time.Millisecond,
+		Observer:     func(a Attempt) { delays = append(delays, a.Delay) },
+	}
+	err := p.Do(context.Background(), func() error { return errTemporary })
+	if !errors.Is(err, errTemporary) {
+		t.Fatalf("期望返回最后一次错误, 实际为 %v", err)
+	}
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 0}
+	if len(delays) != len(want) {
+		t.Fatalf("期望 %d 次尝试, 实际为 %d", len(want), len(delays))
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("期望延迟序列 %v, 实际为 %v", want, delays)
+		}
+	}
+}
+
+func TestPolicyDecorrelatedJitterStaysInBounds(t *testing.T) {
+	p := Policy{Backoff: DecorrelatedJitter, InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	var base time.Duration
+	for i := 0; i < 100; i++ {
+		var wait time.Duration
+		base, wait = p.nextDelay(base)
+		if wait < p.InitialDelay || wait > p.MaxDelay {
+			t.Fatalf("延迟 %s 超出 [%s, %s]", wait, p.InitialDelay, p.MaxDelay)
+		}
+	}
+}
+
+func TestPolicyStopsOnUnretryableAndClassifier(t *testing.T) {
+	calls := 0
+	err := Policy{MaxAttempts: 5}.Do(context.Background(), func() error {
+		calls++
+		return WrapUnretryable(errTemporary)
+	})
+	if calls != 1 || err != errTemporary {
+		t.Errorf("期望不可重试错误只尝试一次并解包, 实际 calls=%d err=%v", calls, err)
+	}
+
+	calls = 0
+	p := Policy{MaxAttempts: 5, Retryable: func(err error) bool { return !errors.Is(err, errTemporary) }}
+	p.Do(context.Background(), func() error {
+		calls++
+		return errTemporary
+	})
+	if calls != 1 {
+		t.Errorf("期望分类器阻止重试, 实际尝试了 %d 次", calls)
+	}
+}
+
+func TestPolicyHonoursRetryAfterAndMaxElapsed(t *testing.T) {
+	var delays []time.Duration
+	p := Policy{
+		MaxAttempts:  10,
+		InitialDelay: time.Millisecond,
+		MaxElapsed:   50 * time.Millisecond,
+		Observer:     func(a Attempt) { delays = append(delays, a.Delay) },
+	}
+	start := time.Now()
+	p.Do(context.Background(), func() error { return WithRetryAfter(errTemporary, 30*time.Millisecond) })
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("期望在 MaxElapsed 附近放弃, 实际耗时 %s", elapsed)
+	}
+	if len(delays) != 2 || delays[0] != 30*time.Millisecond {
+		t.Errorf("期望使用 RetryAfter 提示并在第二次后放弃, 实际延迟为 %v", delays)
+	}
+}
+
+func TestPolicyCapsRetryAfterWithMaxDelay(t *testing.T) {
+	var delays []time.Duration
+	p := Policy{
+		MaxAttempts:  2,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     10 * time.Millisecond,
+		Observer:     func(a Attempt) { delays = append(delays, a.Delay) },
+	}
+	p.Do(context.Background(), func() error { return WithRetryAfter(errTemporary, time.Hour) })
+	if len(delays) != 2 || delays[0] != 10*time.Millisecond {
+		t.Errorf("期望 RetryAfter 提示被 MaxDelay 截断为 10ms, 实际延迟为 %v", delays)
+	}
+}
+
+func TestPolicyStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Policy{MaxAttempts: 5, InitialDelay: time.Hour}.Do(ctx, func() error {
+		calls++
+		cancel()
+		return errTemporary
+	})
+	if calls != 1 || !errors.Is(err, errTemporary) {
+		t.Errorf("期望取消后立即停止, 实际 calls=%d err=%v", calls, err)
+	}
+}
@@ -0,0 +1,83 @@
+// Package secret encrypts small local files, such as saved sessions and
+// credentials, with a key derived from a passphrase.
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+const (
+	saltSize   = 16
+	iterations = 200_000
+	keySize    = 32
+)
+
+// magic prefixes every sealed blob so the format can evolve.
+var magic = []byte("SK1\n")
+
+// ErrOpen is returned when a blob cannot be decrypted, e.g. because the passphrase is wrong.
+var ErrOpen = errors.New("cannot decrypt: wrong passphrase or corrupted data")
+
+// Seal encrypts plaintext with AES-256-GCM under a PBKDF2-SHA256 key derived
+// from passphrase and a random salt.
+func Seal(passphrase string, plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(magic)+saltSize+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, magic), nil
+}
+
+// Open decrypts a blob produced by Seal.
+func Open(passphrase string, sealed []byte) ([]byte, error) {
+	if !bytes.HasPrefix(sealed, magic) {
+		return nil, ErrOpen
+	}
+	sealed = sealed[len(magic):]
+	if len(sealed) < saltSize {
+		return nil, ErrOpen
+	}
+	aead, err := newAEAD(passphrase, sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	sealed = sealed[saltSize:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrOpen
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return plaintext, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
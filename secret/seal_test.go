@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	plaintext := []byte(`{"PHPSESSID":"abc"}`)
+	sealed, err := Seal("correct horse", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("密文中不应出现明文")
+	}
+
+	opened, err := Open("correct horse", sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("解密结果不符: %q, %v", opened, err)
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	sealed, err := Seal("correct horse", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	testCases := []struct {
+		name       string
+		passphrase string
+		data       []byte
+	}{
+		{name: "错误的口令", passphrase: "battery staple", data: sealed},
+		{name: "被篡改的密文", passphrase: "correct horse", data: tampered},
+		{name: "截断的数据", passphrase: "correct horse", data: sealed[:10]},
+		{name: "非加密文件", passphrase: "correct horse", data: []byte("plain text")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Open(tc.passphrase, tc.data); !errors.Is(err, ErrOpen) {
+				t.Errorf("期望 ErrOpen, 实际为 %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,640 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"seat-killer/config"
+	"seat-killer/history"
+	"seat-killer/logging"
+	"seat-killer/mapper"
+	"seat-killer/redact"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultListen = "127.0.0.1:8765"
+	// tokenFile holds the token the API requires, inside the config directory.
+	tokenFile = "web_token"
+	// maxBodyBytes bounds request bodies; user_config.yml is a few kilobytes.
+	maxBodyBytes = 1 << 20
+	// rehearsalLead is how long after the click a dry run's attack phase starts,
+	// leaving time to validate the credentials and log in.
+	rehearsalLead = 20 * time.Second
+)
+
+//go:embed web/index.html
+var indexPage []byte
+
+// serve implements "seat-killer serve": the web UI and its REST API.
+func (c *cli) serve(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	listen := fs.String("listen", defaultListen, "address to serve the web UI on")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	token, err := loadOrCreateToken(filepath.Join(c.paths.dir, tokenFile))
+	if err != nil {
+		fmt.Fprintf(c.stderr, "cannot set up the access token: %v\n", err)
+		return 1
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	if host, _, _ := net.SplitHostPort(*listen); !isLoopback(host) {
+		slog.Warn("The web UI is reachable from other machines; anyone with the token can change the plan", "listen", *listen)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Handler: newWebServer(ctx, c.paths, token).handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	fmt.Fprintf(c.stdout, "Serving the web UI on http://%s/#token=%s\n", listener.Addr(), token)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// loadOrCreateToken returns the token stored at path, creating a random one on first use.
+func loadOrCreateToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	return token, os.WriteFile(path, []byte(token+"\n"), 0o600)
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// webServer answers the REST API. Config writes and dry runs are serialised by mu.
+type webServer struct {
+	// ctx ends dry runs when the server stops.
+	ctx   context.Context
+	paths configPaths
+	token string
+	// now and runDryRun default to time.Now and runAndRecord; tests override them.
+	now       func() time.Time
+	runDryRun func(ctx context.Context, status *runStatus, paths configPaths, opts runOptions) (history.Entry, error)
+	// userInfo is user_info.yml as read at startup, or userInfoErr why it could not be.
+	userInfo    *config.UserInfo
+	userInfoErr error
+
+	mu      sync.Mutex
+	dryRun  *runStatus
+	running bool
+	// lastDryRun is the outcome of the latest finished dry run, nil before the first.
+	lastDryRun *history.Entry
+}
+
+// newWebServer sets up redaction for the accounts of paths once, since the handlers
+// run concurrently; edits to user_info.yml are picked up after a restart.
+func newWebServer(ctx context.Context, paths configPaths, token string) *webServer {
+	if seatCfg, err := config.LoadSeatConfig(paths.seatConfig); err == nil {
+		redact.Default.SetMode(redact.Mode(seatCfg.Global.Redaction.SchoolID))
+	}
+	// The password reference is not resolved: only the school ID and its scheme are shown.
+	userInfo, err := config.ReadUserInfo(paths.userInfo)
+	return &webServer{ctx: ctx, paths: paths, token: token, now: time.Now, runDryRun: runAndRecord, userInfo: userInfo, userInfoErr: err}
+}
+
+func (s *webServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexPage)
+	})
+	mux.Handle("GET /api/config", s.auth(s.getConfig))
+	mux.Handle("PUT /api/config", s.auth(s.putConfig))
+	mux.Handle("PUT /api/config/days/{weekday}", s.auth(s.putDay))
+	mux.Handle("GET /api/accounts", s.auth(s.getAccounts))
+	mux.Handle("GET /api/history", s.auth(s.getHistory))
+	mux.Handle("GET /api/upcoming", s.auth(s.getUpcoming))
+	mux.Handle("GET /api/dry-run", s.auth(s.getDryRun))
+	mux.Handle("POST /api/dry-run", s.auth(s.postDryRun))
+	return mux
+}
+
+// auth rejects requests without "Authorization: Bearer <token>".
+func (s *webServer) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		next(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// dayForm is the editable part of one week_config entry.
+type dayForm struct {
+	Weekday   string   `json:"weekday"`
+	Enable    bool     `json:"enable"`
+	Room      string   `json:"room"`
+	Seats     []string `json:"seats"`
+	RunAt     string   `json:"run_at"`     // "20:00"
+	BookStart string   `json:"book_start"` // "08:30"
+	Duration  string   `json:"duration"`   // "12h" or "3h30m"
+}
+
+type roomView struct {
+	Name  string   `json:"name"`
+	Seats []string `json:"seats"`
+}
+
+// configView is GET /api/config: the file as written, the weekdays as forms, and
+// the rooms of the seat map to pick from. Error is set when the file is invalid,
+// so that it can still be fixed through the raw text.
+type configView struct {
+	YAML  string     `json:"yaml"`
+	Error string     `json:"error,omitempty"`
+	Days  []dayForm  `json:"days"`
+	Rooms []roomView `json:"rooms"`
+}
+
+func (s *webServer) getConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := os.ReadFile(s.paths.seatConfig)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	view := configView{YAML: string(data), Days: []dayForm{}, Rooms: []roomView{}}
+	if seatCfg, err := config.ParseSeatConfig(data); err != nil {
+		view.Error = err.Error()
+	} else {
+		view.Days = dayForms(seatCfg)
+	}
+	if seats, err := mapper.LoadSeatMap(s.paths.seatMap); err == nil {
+		for name, room := range seats {
+			titles := make([]string, len(room))
+			for i, seat := range room {
+				titles[i] = seat.Title
+			}
+			view.Rooms = append(view.Rooms, roomView{Name: name, Seats: titles})
+		}
+		sort.Slice(view.Rooms, func(i, j int) bool { return view.Rooms[i].Name < view.Rooms[j].Name })
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// dayForms lists the weekdays from Monday to Sunday; missing ones come back disabled.
+func dayForms(seatCfg *config.SeatConfig) []dayForm {
+	var forms []dayForm
+	for offset := 0; offset < 7; offset++ {
+		weekday := weekdayNames[time.Weekday((int(time.Monday)+offset)%7)]
+		form := dayForm{Weekday: weekday, Seats: []string{}}
+		if day, ok := seatCfg.WeekConfig[weekday]; ok {
+			form.Enable, form.Room = day.Enable, day.Name
+			if day.Seats != nil {
+				form.Seats = day.Seats
+			}
+			form.RunAt = fmt.Sprintf("%02d:%02d", day.RunAtHour, day.RunAtMinute)
+			form.BookStart = day.BookStart.String()
+			form.Duration = day.Duration.String()
+		}
+		forms = append(forms, form)
+	}
+	return forms
+}
+
+// putConfig replaces user_config.yml with the request body, if it passes validation.
+func (s *webServer) putConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.saveSeatConfig(data); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	slog.Info("Seat config replaced through the web UI", "path", s.paths.seatConfig)
+	s.getConfig(w, r)
+}
+
+// putDay updates one weekday of user_config.yml. Everything else in the file,
+// comments included, is kept as it is.
+func (s *webServer) putDay(w http.ResponseWriter, r *http.Request) {
+	var form dayForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %w", err))
+		return
+	}
+	form.Weekday = r.PathValue("weekday")
+	if !isWeekday(form.Weekday) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown weekday %q, expected 周一 ... 周日", form.Weekday))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.paths.seatConfig)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	updated, err := applyDayForm(data, form)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.saveSeatConfig(updated); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	slog.Info("Weekday updated through the web UI", "weekday", form.Weekday, "enable", form.Enable, "room", form.Room)
+	s.getConfig(w, r)
+}
+
+// saveSeatConfig validates data like a run would and atomically replaces user_config.yml with it.
+func (s *webServer) saveSeatConfig(data []byte) error {
+	return writeValidated(s.paths.seatConfig, string(data), func(path string) error {
+		_, err := config.LoadSeatConfig(path)
+		return err
+	})
+}
+
+func isWeekday(name string) bool {
+	for _, weekday := range weekdayNames {
+		if weekday == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDayForm writes form into the week_config of the YAML document data.
+func applyDayForm(data []byte, form dayForm) ([]byte, error) {
+	runAt, err := config.ParseClockTime(form.RunAt)
+	if err != nil {
+		return nil, fmt.Errorf("run_at: %w", err)
+	}
+	bookStart, err := config.ParseClockTime(form.BookStart)
+	if err != nil {
+		return nil, fmt.Errorf("book_start: %w", err)
+	}
+	duration, err := time.ParseDuration(form.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("duration: 时长格式无效 %q, 应为 12h 或 3h30m", form.Duration)
+	}
+	seats := make([]string, 0, len(form.Seats))
+	for _, seat := range form.Seats {
+		if seat = strings.TrimSpace(seat); seat != "" {
+			seats = append(seats, seat)
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("user_config.yml is not a YAML mapping")
+	}
+	day := mappingValue(mappingValue(doc.Content[0], "week_config"), form.Weekday)
+	seatList := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, seat := range seats {
+		seatList.Content = append(seatList.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seat, Style: yaml.DoubleQuotedStyle})
+	}
+	setValue(day, "启用", scalar("!!bool", strconv.FormatBool(form.Enable)))
+	setValue(day, "run_at_hour", scalar("!!int", strconv.Itoa(runAt.Hour())))
+	setValue(day, "run_at_minute", scalar("!!int", strconv.Itoa(runAt.Minute())))
+	setValue(day, "name", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.TrimSpace(form.Room), Style: yaml.DoubleQuotedStyle})
+	setValue(day, "seats", seatList)
+	// book_start supersedes the legacy book_start_hour; take over its place in the file.
+	renameKey(day, "book_start_hour", "book_start")
+	setValue(day, "book_start", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: bookStart.String(), Style: yaml.DoubleQuotedStyle})
+	setValue(day, "duration", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: config.Duration(duration).String(), Style: yaml.DoubleQuotedStyle})
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// mappingValue returns the mapping stored under key in m, adding an empty one when missing.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			if value := m.Content[i+1]; value.Kind == yaml.MappingNode {
+				return value
+			}
+			m.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			return m.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	m.Content = append(m.Content, scalar("!!str", key), value)
+	return value
+}
+
+// setValue sets key in the mapping m, keeping the comment after the old value.
+func setValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			value.LineComment = m.Content[i+1].LineComment
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, scalar("!!str", key), value)
+}
+
+// renameKey renames from to to in the mapping m, dropping from instead when to is already set.
+func renameKey(m *yaml.Node, from, to string) {
+	hasTo := false
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		hasTo = hasTo || m.Content[i].Value == to
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != from {
+			continue
+		}
+		if hasTo {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+		} else {
+			m.Content[i].Value = to
+		}
+		return
+	}
+}
+
+// accountView is one account of GET /api/accounts. The school ID is redacted and
+// the password never leaves the server.
+type accountView struct {
+	Account string `json:"account"`
+	// PasswordSource is "password", or the scheme of password_ref, e.g. "env".
+	PasswordSource string         `json:"password_source"`
+	LastRun        *history.Entry `json:"last_run,omitempty"`
+}
+
+// getAccounts lists the accounts of the config directory. Each directory holds
+// one user_info.yml, so this is a list of one.
+func (s *webServer) getAccounts(w http.ResponseWriter, r *http.Request) {
+	if s.userInfoErr != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("%s: %w", s.paths.userInfo, s.userInfoErr))
+		return
+	}
+	userInfo := s.userInfo
+	view := accountView{Account: accountLabel(userInfo.SchoolID), PasswordSource: "password"}
+	if userInfo.PasswordRef != "" {
+		scheme, _, _ := strings.Cut(userInfo.PasswordRef, ":")
+		view.PasswordSource = scheme
+	}
+	entries, err := (&history.Store{Path: s.paths.history}).Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Account == view.Account {
+			view.LastRun = &entries[i]
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, []accountView{view})
+}
+
+// getHistory returns the latest runs, oldest first; ?n= limits them (default 50, 0 for all).
+func (s *webServer) getHistory(w http.ResponseWriter, r *http.Request) {
+	limit, ok := queryInt(w, r, "n", 50)
+	if !ok {
+		return
+	}
+	entries, err := (&history.Store{Path: s.paths.history}).Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func queryInt(w http.ResponseWriter, r *http.Request, name string, fallback int) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%s must be a non-negative integer", name))
+		return 0, false
+	}
+	return n, true
+}
+
+// plannedRun is one day of GET /api/upcoming, formatted like the plan command.
+type plannedRun struct {
+	Date     string   `json:"date"`
+	Weekday  string   `json:"weekday"`
+	Skipped  string   `json:"skipped,omitempty"`
+	Room     string   `json:"room,omitempty"`
+	LoginAt  string   `json:"login_at,omitempty"`
+	Preempt  string   `json:"preempt_at,omitempty"`
+	Official string   `json:"official_at,omitempty"`
+	Fallback string   `json:"fallback_end,omitempty"`
+	Target   string   `json:"target,omitempty"`
+	Duration string   `json:"durations,omitempty"`
+	Seats    string   `json:"seats,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// upcomingView is GET /api/upcoming: the next runs and the reservations made by earlier ones.
+type upcomingView struct {
+	Runs         []plannedRun    `json:"runs"`
+	Warnings     []string        `json:"warnings"`
+	Reservations []history.Entry `json:"reservations"`
+}
+
+func (s *webServer) getUpcoming(w http.ResponseWriter, r *http.Request) {
+	days, ok := queryInt(w, r, "days", 7)
+	if !ok {
+		return
+	}
+	seatCfg, err := config.LoadSeatConfig(s.paths.seatConfig)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := mapper.LoadSeatMap(s.paths.seatMap); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	entries, err := (&history.Store{Path: s.paths.history}).Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	now := s.now()
+	plans, warnings := buildPlan(seatCfg, now, max(days, 1))
+	view := upcomingView{Runs: []plannedRun{}, Warnings: warnings, Reservations: history.Upcoming(entries, now)}
+	if view.Warnings == nil {
+		view.Warnings = []string{}
+	}
+	if view.Reservations == nil {
+		view.Reservations = []history.Entry{}
+	}
+	for _, p := range plans {
+		run := plannedRun{Date: p.Date.Format(time.DateOnly), Weekday: p.Weekday, Skipped: p.Skipped, Room: p.Room, Warnings: p.Warnings}
+		if !p.OfficialAt.IsZero() {
+			run.LoginAt = p.LoginAt.Format(time.DateTime)
+			run.Preempt = p.PreemptAt.Format(time.TimeOnly)
+			run.Official = p.OfficialAt.Format(time.TimeOnly)
+			run.Fallback = p.FallbackEnd.Format(time.TimeOnly)
+			run.Target = p.TargetBegin.Format("2006-01-02 15:04")
+			run.Duration = formatDurations(p.Durations)
+			run.Seats = formatSeats(p.Seats)
+		}
+		view.Runs = append(view.Runs, run)
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// dryRunRequest is the body of POST /api/dry-run.
+type dryRunRequest struct {
+	Mock      bool `json:"mock"`
+	MockTaken int  `json:"mock_taken"`
+}
+
+// dryRunView is the state of the web-triggered dry run.
+type dryRunView struct {
+	Running bool           `json:"running"`
+	Status  string         `json:"status,omitempty"`
+	Result  *history.Entry `json:"result,omitempty"`
+}
+
+func (s *webServer) getDryRun(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.dryRunView())
+}
+
+func (s *webServer) dryRunView() dryRunView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	view := dryRunView{Running: s.running, Result: s.lastDryRun}
+	if s.dryRun != nil {
+		view.Status = s.dryRun.String()
+	}
+	return view
+}
+
+// postDryRun starts a dry run of today's task that rehearses the booking window
+// right away instead of at the configured time. Only one runs at a time.
+func (s *webServer) postDryRun(w http.ResponseWriter, r *http.Request) {
+	var req dryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %w", err))
+		return
+	}
+	if req.MockTaken < 0 || (req.MockTaken > 0 && !req.Mock) {
+		writeError(w, http.StatusBadRequest, errors.New("mock_taken must be a non-negative count and requires mock"))
+		return
+	}
+	seatCfg, err := config.LoadSeatConfig(s.paths.seatConfig)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts := runOptions{
+		dryRun:    true,
+		mock:      req.Mock,
+		mockTaken: req.MockTaken,
+		openAt:    s.now().Add(time.Duration(seatCfg.Global.PreemptSeconds)*time.Second + rehearsalLead).Truncate(time.Second),
+	}
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, errors.New("a dry run is already in progress"))
+		return
+	}
+	status := &runStatus{}
+	s.dryRun, s.running = status, true
+	s.mu.Unlock()
+
+	slog.Info("Dry run started through the web UI", "mock", opts.mock, "open_at", opts.openAt.Format(time.TimeOnly))
+	go func() {
+		entry, err := s.runDryRun(s.ctx, status, s.paths, opts)
+		if err != nil {
+			slog.Warn("Web dry run failed", logging.Err(err), "status", status.String())
+		}
+		s.mu.Lock()
+		s.running, s.lastDryRun = false, &entry
+		s.mu.Unlock()
+	}()
+	writeJSON(w, http.StatusAccepted, s.dryRunView())
+}
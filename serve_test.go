@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"seat-killer/config"
+	"seat-killer/history"
+	"seat-killer/redact"
+	"seat-killer/sso"
+)
+
+const serveTestConfig = `global:
+  preempt_seconds: 15  # 提前 15 秒
+week_config:
+  周一: # 预约目标：周三
+    启用: true
+    run_at_hour: 20
+    run_at_minute: 0
+    name: "一楼"
+    seats: ["35"]
+    book_start_hour: 10
+    duration: 12
+`
+
+func newTestWebServer(t *testing.T) (*webServer, http.Handler) {
+	t.Helper()
+	dir := t.TempDir()
+	paths := configPaths{
+		dir:        dir,
+		userInfo:   filepath.Join(dir, userInfoFile),
+		seatConfig: filepath.Join(dir, seatConfigFile),
+		seatMap:    filepath.Join(dir, seatMapFile),
+		history:    filepath.Join(dir, historyFile),
+	}
+	files := map[string]string{
+		paths.seatConfig: serveTestConfig,
+		paths.userInfo:   "school_id: \"23000001\"\npassword: \"hunter22\"\n",
+		paths.seatMap:    "# Room: 一楼\nSeatID: 101, Title: 35\nSeatID: 102, Title: 36\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := newWebServer(context.Background(), paths, "secret-token")
+	return s, s.handler()
+}
+
+func doRequest(t *testing.T, h http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeRequiresToken(t *testing.T) {
+	_, h := newTestWebServer(t)
+	testCases := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"页面无需令牌", "/", "", http.StatusOK},
+		{"缺少令牌", "/api/config", "", http.StatusUnauthorized},
+		{"令牌错误", "/api/config", "wrong", http.StatusUnauthorized},
+		{"令牌正确", "/api/config", "secret-token", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if rec := doRequest(t, h, http.MethodGet, tc.path, "", tc.token); rec.Code != tc.want {
+				t.Errorf("期望状态码 %d, 实际为 %d: %s", tc.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestServePutDay(t *testing.T) {
+	s, h := newTestWebServer(t)
+
+	rec := doRequest(t, h, http.MethodPut, "/api/config/days/周一",
+		`{"enable":true,"room":"一楼","seats":["35"],"run_at":"20:00","book_start":"21:00","duration":"4h"}`, "secret-token")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "配置校验失败") {
+		t.Fatalf("超出闭馆时间的计划应被拒绝, 实际为 %d: %s", rec.Code, rec.Body)
+	}
+	if data, _ := os.ReadFile(s.paths.seatConfig); string(data) != serveTestConfig {
+		t.Fatalf("校验失败时不应修改配置文件:\n%s", data)
+	}
+
+	rec = doRequest(t, h, http.MethodPut, "/api/config/days/周三",
+		`{"enable":true,"room":"一楼","seats":["36"," 35 ",""],"run_at":"19:30","book_start":"08:30","duration":"3h30m"}`, "secret-token")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望保存成功, 实际为 %d: %s", rec.Code, rec.Body)
+	}
+	seatCfg, err := config.LoadSeatConfig(s.paths.seatConfig)
+	if err != nil {
+		t.Fatalf("保存后的配置无法加载: %v", err)
+	}
+	wednesday := seatCfg.WeekConfig["周三"]
+	if !wednesday.Enable || wednesday.RunAtHour != 19 || wednesday.RunAtMinute != 30 || strings.Join(wednesday.Seats, ",") != "36,35" ||
+		wednesday.BookStart.String() != "08:30" || wednesday.Duration.Std() != 3*time.Hour+30*time.Minute {
+		t.Errorf("周三的设置错误: %+v", wednesday)
+	}
+	if monday := seatCfg.WeekConfig["周一"]; monday.BookStart.String() != "10:00" || monday.Duration.Std() != 12*time.Hour {
+		t.Errorf("其他日期不应被修改: %+v", monday)
+	}
+	data, _ := os.ReadFile(s.paths.seatConfig)
+	if !strings.Contains(string(data), "# 提前 15 秒") || !strings.Contains(string(data), "# 预约目标：周三") {
+		t.Errorf("保存后应保留注释:\n%s", data)
+	}
+
+	if rec := doRequest(t, h, http.MethodPut, "/api/config/days/星期八", `{}`, "secret-token"); rec.Code != http.StatusNotFound {
+		t.Errorf("未知日期期望 404, 实际为 %d", rec.Code)
+	}
+}
+
+func TestServePutDayKeepsFileMode(t *testing.T) {
+	s, h := newTestWebServer(t)
+	if err := os.Chmod(s.paths.seatConfig, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := doRequest(t, h, http.MethodPut, "/api/config/days/周三",
+		`{"enable":true,"room":"一楼","seats":["36"],"run_at":"19:30","book_start":"08:30","duration":"4h"}`, "secret-token")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望保存成功, 实际为 %d: %s", rec.Code, rec.Body)
+	}
+	info, err := os.Stat(s.paths.seatConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0o644 {
+		t.Errorf("保存后文件权限应保持 0644, 实际为 %#o", got)
+	}
+}
+
+func TestServePutConfigRejectsBadResponseRules(t *testing.T) {
+	s, h := newTestWebServer(t)
+	testCases := []struct {
+		name        string
+		rule        string
+		errContains string
+	}{
+		{"未知的类型", "    - kind: seat_gone\n      contains: [\"没了\"]\n", "'kind'(seat_gone)无效"},
+		{"缺少匹配条件", "    - kind: seat_taken\n", "必须设置'code'或'contains'"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := strings.Replace(serveTestConfig, "global:\n", "global:\n  response_rules:\n"+tc.rule, 1)
+			rec := doRequest(t, h, http.MethodPut, "/api/config", body, "secret-token")
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tc.errContains) {
+				t.Fatalf("期望 400 且包含 %q, 实际为 %d: %s", tc.errContains, rec.Code, rec.Body)
+			}
+			if data, _ := os.ReadFile(s.paths.seatConfig); string(data) != serveTestConfig {
+				t.Fatalf("校验失败时不应修改配置文件:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestApplyDayFormReplacesBookStartHour(t *testing.T) {
+	out, err := applyDayForm([]byte(serveTestConfig), dayForm{Weekday: "周一", Enable: true, Room: "一楼", Seats: []string{"35"}, RunAt: "20:00", BookStart: "09:00", Duration: "12h"})
+	if err != nil {
+		t.Fatalf("applyDayForm 返回错误: %v", err)
+	}
+	text := string(out)
+	if strings.Contains(text, "book_start_hour") || strings.Index(text, "book_start:") > strings.Index(text, "duration:") {
+		t.Errorf("book_start 应原位替换 book_start_hour:\n%s", text)
+	}
+}
+
+func TestServeAccountsHidePassword(t *testing.T) {
+	s, h := newTestWebServer(t)
+	if err := (&history.Store{Path: s.paths.history}).Append(history.Entry{Time: time.Now(), Account: "23****01", Outcome: history.Failed}); err != nil {
+		t.Fatal(err)
+	}
+	rec := doRequest(t, h, http.MethodGet, "/api/accounts", "", "secret-token")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望 200, 实际为 %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "hunter22") || strings.Contains(body, "23000001") {
+		t.Fatalf("账号列表泄露了密码或学号: %s", body)
+	}
+	var accounts []accountView
+	if err := json.Unmarshal(rec.Body.Bytes(), &accounts); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].Account != "23****01" || accounts[0].PasswordSource != "password" || accounts[0].LastRun == nil {
+		t.Errorf("账号列表错误: %s", body)
+	}
+
+	// The redaction mode is set when the server starts, not by every request.
+	redact.Default.SetMode(redact.ModeFull)
+	defer redact.Default.SetMode(redact.ModePartial)
+	doRequest(t, h, http.MethodGet, "/api/accounts", "", "secret-token")
+	if got := redact.String("23000001"); got != "****" {
+		t.Errorf("请求不应修改遮盖方式, 实际为 %q", got)
+	}
+}
+
+func TestServeDryRun(t *testing.T) {
+	s, h := newTestWebServer(t)
+	now := time.Date(2026, 10, 19, 14, 0, 0, 0, time.Local)
+	s.now = func() time.Time { return now }
+	started := make(chan runOptions, 1)
+	release := make(chan struct{})
+	s.runDryRun = func(ctx context.Context, status *runStatus, paths configPaths, opts runOptions) (history.Entry, error) {
+		started <- opts
+		<-release
+		return history.Entry{Outcome: history.Booked, DryRun: true}, nil
+	}
+
+	if rec := doRequest(t, h, http.MethodPost, "/api/dry-run", `{"mock":true}`, "secret-token"); rec.Code != http.StatusAccepted {
+		t.Fatalf("期望 202, 实际为 %d: %s", rec.Code, rec.Body)
+	}
+	opts := <-started
+	if !opts.dryRun || !opts.mock || !opts.openAt.Equal(now.Add(15*time.Second+rehearsalLead)) {
+		t.Errorf("演练参数错误: %+v", opts)
+	}
+	if rec := doRequest(t, h, http.MethodPost, "/api/dry-run", `{}`, "secret-token"); rec.Code != http.StatusConflict {
+		t.Errorf("演练进行中时期望 409, 实际为 %d", rec.Code)
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for s.dryRunView().Running && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if view := s.dryRunView(); view.Running || view.Result == nil || view.Result.Outcome != history.Booked {
+		t.Errorf("演练结束后的状态错误: %+v", view)
+	}
+	if rec := doRequest(t, h, http.MethodPost, "/api/dry-run", `{"mock_taken":1}`, "secret-token"); rec.Code != http.StatusBadRequest {
+		t.Errorf("没有 mock 时 mock_taken 期望 400, 实际为 %d", rec.Code)
+	}
+}
+
+func TestServeKeepsItsLoggerAfterADryRun(t *testing.T) {
+	var serverLog bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&serverLog, nil)))
+	defer slog.SetDefault(previous)
+
+	s, h := newTestWebServer(t)
+	// With the server's context done, the run stops at its first login, after it
+	// has installed its own logger, login provider and redaction mode.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ctx = ctx
+	logDir := filepath.Join(s.paths.dir, "logs")
+	seatConfig := strings.Replace(serveTestConfig, "global:\n", "global:\n  redaction:\n    school_id: full\n  login_provider: hdulib\n  log:\n    dir: "+logDir+"\n", 1)
+	if err := os.WriteFile(s.paths.seatConfig, []byte(seatConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mode, provider := redact.Default.Mode(), sso.Provider()
+
+	if rec := doRequest(t, h, http.MethodPost, "/api/dry-run", `{}`, "secret-token"); rec.Code != http.StatusAccepted {
+		t.Fatalf("期望 202, 实际为 %d: %s", rec.Code, rec.Body)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for s.dryRunView().Running && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if view := s.dryRunView(); view.Running || view.Result == nil || view.Result.Outcome != history.Error {
+		t.Fatalf("演练应在登录时因取消而结束, 实际为 %+v", view)
+	}
+
+	slog.Info("still serving")
+	if !strings.Contains(serverLog.String(), "still serving") {
+		t.Errorf("演练结束后服务器的日志应写回原来的输出:\n%s", serverLog.String())
+	}
+	files, _ := filepath.Glob(filepath.Join(logDir, "*.log"))
+	if len(files) == 0 {
+		t.Fatal("演练应写入自己的日志文件")
+	}
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		if strings.Contains(string(data), "still serving") {
+			t.Errorf("演练结束后不应再写入演练的日志文件 %s", file)
+		}
+		if strings.Contains(string(data), "23****01") || !strings.Contains(string(data), "account=****") {
+			t.Errorf("演练的日志应按配置完全遮盖学号:\n%s", data)
+		}
+	}
+	if got := redact.Default.Mode(); got != mode {
+		t.Errorf("演练结束后遮盖方式应恢复为 %s, 实际为 %s", mode, got)
+	}
+	if got := sso.Provider(); got != provider {
+		t.Errorf("演练结束后登录方式应恢复为 %s, 实际为 %s", provider.Name(), got.Name())
+	}
+}
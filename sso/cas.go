@@ -0,0 +1,153 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"seat-killer/logging"
+	"seat-killer/retry"
+
+	hdusso "github.com/hduLib/hdu/sso"
+)
+
+// The login page embeds the CAS flow key and the per-page AES key used to encrypt the password.
+var (
+	executionRegexp = regexp.MustCompile(`id="login-page-flowkey"[^>]*>([^<]+)`)
+	croyptoRegexp   = regexp.MustCompile(`id="login-croypto"[^>]*>([^<]+)`)
+	titleRegexp     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// ErrBadCredentials is returned when the CAS server shows the login page again after submitting.
+var ErrBadCredentials = errors.New("login failed, please check your school ID and password")
+
+// CASProvider is the in-repo implementation of the sso.hdu.edu.cn CAS flow.
+// Every request is bound to ctx and uses the given client only, so concurrent
+// logins with different clients do not interfere.
+type CASProvider struct {
+	// Debug logs every step of the flow at info level. Passwords are never logged.
+	Debug bool
+}
+
+// Name implements LoginProvider.
+func (p *CASProvider) Name() string { return "native" }
+
+// Login implements LoginProvider.
+func (p *CASProvider) Login(ctx context.Context, c *http.Client, loginURL, user, passwd string) error {
+	// 1. Fetch the login page for the execution token and the encryption key.
+	p.tracef(ctx, "step 1/4: GET %s", loginURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loginURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching login page: %w", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("reading login page: %w", err)
+	}
+	p.tracef(ctx, "step 1/4: status %d from %s, %d bytes, title %q", resp.StatusCode, resp.Request.URL, len(body), pageTitle(body))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login page returned status %d", resp.StatusCode)
+	}
+	execution, err := extract(executionRegexp, body, "execution")
+	if err != nil {
+		return err
+	}
+	croypto, err := extract(croyptoRegexp, body, "croypto")
+	if err != nil {
+		return err
+	}
+	p.tracef(ctx, "step 2/4: found execution (%d chars) and croypto (%d chars)", len(execution), len(croypto))
+
+	// 2. Encrypt the password with the page key.
+	encrypted, err := hdusso.AesEncrypt(croypto, passwd)
+	if err != nil {
+		return fmt.Errorf("encrypting password: %w", err)
+	}
+
+	// 3. Submit the form; the client follows the service ticket redirects.
+	form := url.Values{}
+	form.Set("username", user)
+	form.Set("password", encrypted)
+	form.Set("execution", execution)
+	form.Set("croypto", croypto)
+	form.Set("type", "UsernamePassword")
+	form.Set("_eventId", "submit")
+	form.Set("geolocation", "")
+	p.tracef(ctx, "step 3/4: POST credentials to %s", resp.Request.URL)
+	postReq, err := http.NewRequestWithContext(ctx, http.MethodPost, resp.Request.URL.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	postReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	postReq.Header.Set("Referer", resp.Request.URL.String())
+	finalResp, err := c.Do(postReq)
+	if err != nil {
+		return fmt.Errorf("submitting login form: %w", err)
+	}
+	finalBody, _ := io.ReadAll(finalResp.Body)
+	finalResp.Body.Close()
+	for _, hop := range redirectChain(finalResp) {
+		p.tracef(ctx, "step 4/4: redirect %s", hop)
+	}
+	p.tracef(ctx, "step 4/4: status %d at %s, title %q", finalResp.StatusCode, withoutQuery(finalResp.Request.URL), pageTitle(finalBody))
+
+	// 4. A successful login leaves the SSO host.
+	if finalResp.Request.URL.Host == resp.Request.URL.Host {
+		return retry.WrapUnretryable(ErrBadCredentials)
+	}
+	return nil
+}
+
+func (p *CASProvider) tracef(ctx context.Context, format string, args ...any) {
+	level := slog.LevelDebug
+	if p.Debug {
+		level = slog.LevelInfo
+	}
+	logger := logging.From(ctx)
+	if logger.Enabled(ctx, level) {
+		logger.Log(ctx, level, "sso login step", "step", fmt.Sprintf(format, args...))
+	}
+}
+
+func extract(re *regexp.Regexp, page []byte, name string) (string, error) {
+	match := re.FindSubmatch(page)
+	if len(match) < 2 {
+		return "", fmt.Errorf("%s not found on the login page (title %q), the SSO page may have changed", name, pageTitle(page))
+	}
+	return strings.TrimSpace(string(match[1])), nil
+}
+
+func pageTitle(page []byte) string {
+	match := titleRegexp.FindSubmatch(page)
+	if len(match) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(string(match[1]))
+}
+
+// redirectChain lists the redirects that led to resp, oldest first. Query strings
+// are dropped because they carry service tickets.
+func redirectChain(resp *http.Response) []string {
+	var hops []string
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		hops = append([]string{fmt.Sprintf("%d %s %s", r.StatusCode, r.Request.Method, withoutQuery(r.Request.URL))}, hops...)
+	}
+	return hops
+}
+
+func withoutQuery(u *url.URL) string {
+	stripped := *u
+	stripped.RawQuery = ""
+	return stripped.String()
+}
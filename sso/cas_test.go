@@ -0,0 +1,113 @@
+package sso
+
+import (
+	"context"
+	"crypto/aes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// fakeCASKey is the base64 AES key the fake login page hands out as croypto.
+const fakeCASKey = "MDEyMzQ1Njc4OWFiY2RlZg=="
+
+// newFakeCAS starts a library service and a CAS server that accepts any user
+// whose password is "pw-"+username, and issues PHPSESSID "sess-"+username.
+func newFakeCAS(t *testing.T) (casURL, serviceURL string) {
+	t.Helper()
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" {
+			http.Error(w, "missing ticket", http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "sess-" + ticket[len("ST-"):], Path: "/"})
+		fmt.Fprint(w, "ok")
+	}))
+	t.Cleanup(service.Close)
+
+	cas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "flow", Path: "/"})
+			fmt.Fprintf(w, `<p id="login-page-flowkey" style="display:none">e1s1</p><p id="login-croypto">%s</p>`, fakeCASKey)
+			return
+		}
+		if c, err := r.Cookie("SESSION"); err != nil || c.Value != "flow" {
+			http.Error(w, "no flow session", http.StatusBadRequest)
+			return
+		}
+		user := r.PostFormValue("username")
+		passwd, err := decryptECB(fakeCASKey, r.PostFormValue("password"))
+		if err != nil || r.PostFormValue("execution") != "e1s1" || passwd != "pw-"+user {
+			fmt.Fprint(w, `<p id="login-page-flowkey">e1s2</p>`)
+			return
+		}
+		http.Redirect(w, r, service.URL+"/login?ticket=ST-"+url.QueryEscape(user), http.StatusFound)
+	}))
+	t.Cleanup(cas.Close)
+	return cas.URL + "/login", service.URL
+}
+
+func decryptECB(key, text string) (string, error) {
+	keyBytes, _ := base64.StdEncoding.DecodeString(key)
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return "", errors.New("bad ciphertext")
+	}
+	plain := make([]byte, len(data))
+	for bs := 0; bs < len(data); bs += block.BlockSize() {
+		block.Decrypt(plain[bs:bs+block.BlockSize()], data[bs:bs+block.BlockSize()])
+	}
+	return string(plain[:len(plain)-int(plain[len(plain)-1])]), nil
+}
+
+func TestConcurrentLogin(t *testing.T) {
+	casURL, serviceURL := newFakeCAS(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		user := fmt.Sprintf("2300%02d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, sessID, err := login(context.Background(), casURL, serviceURL, user, "pw-"+user)
+			if err != nil {
+				t.Errorf("账号 %s 登录失败: %v", user, err)
+				return
+			}
+			if client == nil || sessID != "sess-"+user {
+				t.Errorf("账号 %s 拿到了错误的会话 %q", user, sessID)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoginBadPassword(t *testing.T) {
+	casURL, serviceURL := newFakeCAS(t)
+
+	_, _, err := login(context.Background(), casURL, serviceURL, "230001", "wrong")
+	if !errors.Is(err, ErrBadCredentials) {
+		t.Fatalf("期望 ErrBadCredentials, 实际为 %v", err)
+	}
+}
+
+func TestLoginCancelled(t *testing.T) {
+	casURL, serviceURL := newFakeCAS(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := login(ctx, casURL, serviceURL, "230001", "pw-230001")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("期望 context.Canceled, 实际为 %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package sso
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"seat-killer/redact"
+	"seat-killer/secret"
+)
+
+// sessionOrigin is the origin whose cookies make up a saved session. Only the
+// library's cookies are kept: a re-login always starts a fresh CAS login, so the
+// SSO ticket-granting cookie, which opens every campus service, is never written.
+const sessionOrigin = libraryURL
+
+// savedSession is the plaintext of a session file.
+type savedSession struct {
+	SchoolID string                   `json:"school_id"`
+	SavedAt  time.Time                `json:"saved_at"`
+	Cookies  map[string][]savedCookie `json:"cookies"` // by origin
+}
+
+type savedCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// saveClient writes the library cookies c holds to path, encrypted with passwd.
+func saveClient(path, schoolID, passwd string, c *http.Client) error {
+	saved := savedSession{SchoolID: schoolID, SavedAt: time.Now(), Cookies: make(map[string][]savedCookie)}
+	u, err := url.Parse(sessionOrigin)
+	if err != nil {
+		return err
+	}
+	for _, cookie := range c.Jar.Cookies(u) {
+		saved.Cookies[sessionOrigin] = append(saved.Cookies[sessionOrigin], savedCookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	plaintext, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	sealed, err := secret.Seal(passwd, plaintext)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadClient rebuilds a client from a session file written by saveClient. Cookies
+// of other origins, saved by earlier versions, are ignored.
+func loadClient(path, schoolID, passwd string) (*http.Client, time.Time, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	plaintext, err := secret.Open(passwd, sealed)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var saved savedSession
+	if err := json.Unmarshal(plaintext, &saved); err != nil {
+		return nil, time.Time{}, err
+	}
+	if saved.SchoolID != schoolID {
+		return nil, time.Time{}, fmt.Errorf("session file belongs to another account")
+	}
+	c, err := newClient()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	u, err := url.Parse(sessionOrigin)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	cookies := saved.Cookies[sessionOrigin]
+	restored := make([]*http.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		redact.AddSecret(cookie.Value)
+		restored = append(restored, &http.Cookie{Name: cookie.Name, Value: cookie.Value, Path: "/"})
+	}
+	c.Jar.SetCookies(u, restored)
+	return c, saved.SavedAt, nil
+}
@@ -0,0 +1,148 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"seat-killer/secret"
+)
+
+func savedTestSession(t *testing.T, schoolID, passwd, sessID string) string {
+	t.Helper()
+	c, err := newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(libraryURL)
+	c.Jar.SetCookies(u, []*http.Cookie{{Name: "PHPSESSID", Value: sessID, Path: "/"}})
+	path := filepath.Join(t.TempDir(), "sessions", schoolID+".session")
+	if err := saveClient(path, schoolID, passwd, c); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadClient(t *testing.T) {
+	path := savedTestSession(t, "230001", "pw", "abc")
+
+	testCases := []struct {
+		name      string
+		schoolID  string
+		passwd    string
+		expectErr bool
+	}{
+		{name: "正确的账号和密码", schoolID: "230001", passwd: "pw"},
+		{name: "密码已修改", schoolID: "230001", passwd: "new-pw", expectErr: true},
+		{name: "其他账号", schoolID: "230002", passwd: "pw", expectErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _, err := loadClient(path, tc.schoolID, tc.passwd)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("期望出现错误，但返回的错误为 nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("不期望出现错误，但收到了错误: %v", err)
+			}
+			if got := sessionCookie(c, libraryURL); got != "abc" {
+				t.Errorf("期望恢复 PHPSESSID abc, 实际为 %q", got)
+			}
+		})
+	}
+}
+
+func TestSessionResume(t *testing.T) {
+	path := savedTestSession(t, "230001", "pw", "abc")
+	checkCookie := func(_ context.Context, c *http.Client) error {
+		if sessionCookie(c, libraryURL) != "abc" {
+			return errors.New("expired")
+		}
+		return nil
+	}
+
+	s := NewSession("230001", "pw", 1)
+	s.Persist(path)
+	if !s.Resume(context.Background(), checkCookie) {
+		t.Fatal("期望复用保存的会话")
+	}
+	if s.Client() == nil {
+		t.Fatal("复用后应有可用的客户端")
+	}
+
+	expired := func(context.Context, *http.Client) error { return errors.New("expired") }
+	if s.Resume(context.Background(), expired) {
+		t.Fatal("会话失效时不应复用")
+	}
+	if s.Client() != nil {
+		t.Error("失效的会话应被丢弃")
+	}
+
+	missing := NewSession("230001", "pw", 1)
+	missing.Persist(filepath.Join(t.TempDir(), "none.session"))
+	if missing.Resume(context.Background(), checkCookie) {
+		t.Error("没有会话文件时不应复用")
+	}
+}
+
+func TestSaveClientKeepsOnlyTheLibrarySession(t *testing.T) {
+	c, err := newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	library, _ := url.Parse(libraryURL)
+	c.Jar.SetCookies(library, []*http.Cookie{{Name: "PHPSESSID", Value: "abc", Path: "/"}})
+	cas, _ := url.Parse("https://sso.hdu.edu.cn")
+	c.Jar.SetCookies(cas, []*http.Cookie{{Name: "CASTGC", Value: "TGT-1-secret", Path: "/"}})
+	path := filepath.Join(t.TempDir(), "230001.session")
+	if err := saveClient(path, "230001", "pw", c); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := secret.Open("pw", sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plaintext), "TGT-1-secret") || !strings.Contains(string(plaintext), "abc") {
+		t.Fatalf("会话文件只应保存图书馆的 Cookie, 实际为 %s", plaintext)
+	}
+}
+
+func TestLoadClientIgnoresSSOCookiesOfOldFiles(t *testing.T) {
+	plaintext, _ := json.Marshal(savedSession{SchoolID: "230001", Cookies: map[string][]savedCookie{
+		libraryURL:               {{Name: "PHPSESSID", Value: "abc"}},
+		"https://sso.hdu.edu.cn": {{Name: "CASTGC", Value: "TGT-1-secret"}},
+	}})
+	sealed, err := secret.Seal("pw", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "230001.session")
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, _, err := loadClient(path, "230001", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cas, _ := url.Parse("https://sso.hdu.edu.cn")
+	if got := c.Jar.Cookies(cas); len(got) != 0 {
+		t.Errorf("不应恢复 SSO 的 Cookie, 实际为 %v", got)
+	}
+	if got := sessionCookie(c, libraryURL); got != "abc" {
+		t.Errorf("期望恢复 PHPSESSID abc, 实际为 %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+package sso
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/hduLib/hdu/client"
+	hdusso "github.com/hduLib/hdu/sso"
+)
+
+// LoginProvider runs an SSO login flow with c. On success the service session
+// is left in c's cookie jar.
+type LoginProvider interface {
+	Name() string
+	Login(ctx context.Context, c *http.Client, loginURL, user, passwd string) error
+}
+
+// provider is used by Login; see SetProvider.
+var provider LoginProvider = &CASProvider{}
+
+// SetProvider replaces the login implementation. It must be called before the first login.
+func SetProvider(p LoginProvider) {
+	provider = p
+}
+
+// Provider returns the login implementation set by SetProvider.
+func Provider() LoginProvider {
+	return provider
+}
+
+// NewProvider returns the provider with the given name: "native" for the in-repo
+// CAS flow or "hdulib" for github.com/hduLib/hdu.
+func NewProvider(name string, debug bool) (LoginProvider, error) {
+	switch name {
+	case "", "native":
+		return &CASProvider{Debug: debug}, nil
+	case "hdulib":
+		return &HDULibProvider{}, nil
+	}
+	return nil, fmt.Errorf("unknown login provider %q", name)
+}
+
+// hduLibMu serialises HDULibProvider logins, since the library reads the
+// package-level client.DefaultClient.
+var hduLibMu sync.Mutex
+
+// HDULibProvider logs in through github.com/hduLib/hdu/sso.GenLoginReq.
+// The library keeps its client in a global, so logins are serialised.
+type HDULibProvider struct{}
+
+// Name implements LoginProvider.
+func (p *HDULibProvider) Name() string { return "hdulib" }
+
+// Login implements LoginProvider.
+func (p *HDULibProvider) Login(ctx context.Context, c *http.Client, loginURL, user, passwd string) error {
+	hduLibMu.Lock()
+	defer hduLibMu.Unlock()
+
+	// The library builds its requests without a context; attach ctx in the transport.
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	libClient := *c
+	libClient.Transport = &contextTransport{RoundTripper: base, ctx: ctx}
+	previous := client.DefaultClient
+	client.DefaultClient = &libClient
+	defer func() { client.DefaultClient = previous }()
+
+	_, err := hdusso.GenLoginReq(loginURL, user, passwd)
+	return err
+}
+
+// contextTransport attaches ctx to requests that were built without one.
+type contextTransport struct {
+	http.RoundTripper
+	ctx context.Context
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context() == context.Background() {
+		req = req.WithContext(t.ctx)
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
@@ -0,0 +1,85 @@
+package sso
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hduLib/hdu/client"
+)
+
+func newJarClient(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+func sessionCookie(c *http.Client, serviceURL string) string {
+	u, _ := url.Parse(serviceURL)
+	for _, cookie := range c.Jar.Cookies(u) {
+		if cookie.Name == "PHPSESSID" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func TestCASProviderTrace(t *testing.T) {
+	casURL, serviceURL := newFakeCAS(t)
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(prev)
+
+	c := newJarClient(t)
+	if err := (&CASProvider{Debug: true}).Login(context.Background(), c, casURL, "230001", "pw-230001"); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if got := sessionCookie(c, serviceURL); got != "sess-230001" {
+		t.Errorf("期望会话 sess-230001, 实际为 %q", got)
+	}
+	out := buf.String()
+	for _, step := range []string{"step 1/4", "step 2/4", "step 3/4", "step 4/4: redirect 302 POST"} {
+		if !strings.Contains(out, step) {
+			t.Errorf("调试日志缺少 %q:\n%s", step, out)
+		}
+	}
+	if strings.Contains(out, "pw-230001") || strings.Contains(out, "ticket=") || strings.Contains(out, "230001") {
+		t.Errorf("调试日志泄露了密码、票据或学号:\n%s", out)
+	}
+}
+
+func TestHDULibProvider(t *testing.T) {
+	casURL, serviceURL := newFakeCAS(t)
+	previous := client.DefaultClient
+
+	c := newJarClient(t)
+	if err := (&HDULibProvider{}).Login(context.Background(), c, casURL, "230002", "pw-230002"); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if got := sessionCookie(c, serviceURL); got != "sess-230002" {
+		t.Errorf("期望会话 sess-230002, 实际为 %q", got)
+	}
+	if client.DefaultClient != previous {
+		t.Error("登录后应恢复 hdu 库的全局客户端")
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	for _, name := range []string{"", "native", "hdulib"} {
+		if _, err := NewProvider(name, false); err != nil {
+			t.Errorf("NewProvider(%q) 返回错误: %v", name, err)
+		}
+	}
+	if _, err := NewProvider("oauth", false); err == nil {
+		t.Error("期望未知的登录方式返回错误")
+	}
+}
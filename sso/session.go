@@ -0,0 +1,147 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"seat-killer/logging"
+)
+
+// ErrReloginLimit is returned by Relogin once the session has used up its re-logins.
+var ErrReloginLimit = errors.New("re-login limit reached")
+
+// Session is a logged-in library session that can renew itself when the server
+// invalidates the PHPSESSID. It is safe for concurrent use.
+type Session struct {
+	schoolID    string
+	password    string
+	maxRelogins int
+
+	// path is the encrypted session file; empty disables persistence.
+	path string
+
+	// OnLogin, if set, is called after every CAS login attempt, e.g. to count them.
+	OnLogin func(elapsed time.Duration, err error)
+
+	mu       sync.Mutex
+	client   *http.Client
+	relogins int
+}
+
+// NewSession creates a session for the given credentials. maxRelogins bounds how
+// many times Relogin may log in again over the lifetime of the session.
+func NewSession(schoolID, password string, maxRelogins int) *Session {
+	return &Session{schoolID: schoolID, password: password, maxRelogins: maxRelogins}
+}
+
+// Persist makes Resume read the session from path and every successful login
+// write it back, encrypted with the account password. Call it before Login.
+func (s *Session) Persist(path string) {
+	s.path = path
+}
+
+// Resume adopts the saved session, or keeps the current one, if validate accepts
+// it. It reports whether the session is usable without logging in.
+func (s *Session) Resume(ctx context.Context, validate func(context.Context, *http.Client) error) bool {
+	logger := logging.From(ctx)
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+	if client == nil {
+		if s.path == "" {
+			return false
+		}
+		var savedAt time.Time
+		var err error
+		client, savedAt, err = loadClient(s.path, s.schoolID, s.password)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger.Warn("Ignoring saved session", logging.Err(err))
+			}
+			return false
+		}
+		logger.Info("Found saved session, checking it", "saved_at", savedAt.Format(time.DateTime))
+	}
+	if err := validate(ctx, client); err != nil {
+		logger.Info("Session is no longer valid", logging.Err(err))
+		s.mu.Lock()
+		if s.client == client {
+			s.client = nil
+		}
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.Lock()
+	s.client = client
+	s.mu.Unlock()
+	return true
+}
+
+// Login performs the initial login.
+func (s *Session) Login(ctx context.Context) error {
+	client, err := s.login(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.client = client
+	s.mu.Unlock()
+	s.save(client)
+	return nil
+}
+
+// login runs one CAS login and reports it to OnLogin.
+func (s *Session) login(ctx context.Context) (*http.Client, error) {
+	start := time.Now()
+	client, _, err := Login(ctx, s.schoolID, s.password)
+	if s.OnLogin != nil {
+		s.OnLogin(time.Since(start), err)
+	}
+	return client, err
+}
+
+// save writes the session file. Failing to save only costs a login next time.
+func (s *Session) save(client *http.Client) {
+	if s.path == "" {
+		return
+	}
+	if err := saveClient(s.path, s.schoolID, s.password, client); err != nil {
+		slog.Warn("Could not save session", logging.Account(s.schoolID), logging.Err(err))
+	}
+}
+
+// Client returns the HTTP client carrying the current session cookies.
+func (s *Session) Client() *http.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// Relogin replaces the session after the server rejected stale, the client the caller used.
+// If another caller already renewed the session since stale was handed out, it returns
+// immediately so concurrent failures only trigger one login.
+func (s *Session) Relogin(ctx context.Context, stale *http.Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != stale {
+		return nil
+	}
+	if s.relogins >= s.maxRelogins {
+		return fmt.Errorf("%w (%d)", ErrReloginLimit, s.maxRelogins)
+	}
+	s.relogins++
+	logging.From(ctx).Warn("Session expired, logging in again", "relogin", s.relogins, "max_relogins", s.maxRelogins)
+	client, err := s.login(ctx)
+	if err != nil {
+		return fmt.Errorf("re-login failed: %w", err)
+	}
+	s.client = client
+	s.save(client)
+	return nil
+}
@@ -0,0 +1,82 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// countingProvider issues PHPSESSID "sess-<n>" for the n-th login, or fails with err.
+type countingProvider struct {
+	logins int
+	err    error
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Login(_ context.Context, c *http.Client, _, _, _ string) error {
+	p.logins++
+	if p.err != nil {
+		return p.err
+	}
+	u, _ := url.Parse(libraryURL)
+	c.Jar.SetCookies(u, []*http.Cookie{{Name: "PHPSESSID", Value: fmt.Sprintf("sess-%d", p.logins), Path: "/"}})
+	return nil
+}
+
+func TestSessionRelogin(t *testing.T) {
+	loginErr := errors.New("cas unavailable")
+	testCases := []struct {
+		name        string
+		maxRelogins int
+		loginErr    error
+		// staleOther hands Relogin a client other than the current one.
+		staleOther bool
+		wantErr    []error // one entry per Relogin call; nil means success
+		wantLogins int
+		wantCookie string
+	}{
+		{name: "会话过期时重新登录", maxRelogins: 2, wantErr: []error{nil}, wantLogins: 2, wantCookie: "sess-2"},
+		{name: "达到上限后返回 ErrReloginLimit", maxRelogins: 1, wantErr: []error{nil, ErrReloginLimit}, wantLogins: 2, wantCookie: "sess-2"},
+		{name: "max_relogins 为 0 时不重新登录", maxRelogins: 0, wantErr: []error{ErrReloginLimit}, wantLogins: 1, wantCookie: "sess-1"},
+		{name: "其他调用者已续期时不重复登录", maxRelogins: 2, staleOther: true, wantErr: []error{nil}, wantLogins: 1, wantCookie: "sess-1"},
+		{name: "重新登录失败", maxRelogins: 2, loginErr: loginErr, wantErr: []error{loginErr}, wantLogins: 2, wantCookie: "sess-1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &countingProvider{}
+			previous := provider
+			SetProvider(fake)
+			defer SetProvider(previous)
+
+			s := NewSession("230001", "pw", tc.maxRelogins)
+			var reported []error
+			s.OnLogin = func(_ time.Duration, err error) { reported = append(reported, err) }
+			if err := s.Login(context.Background()); err != nil {
+				t.Fatalf("首次登录失败: %v", err)
+			}
+			fake.err = tc.loginErr
+
+			for i, want := range tc.wantErr {
+				stale := s.Client()
+				if tc.staleOther {
+					stale = &http.Client{}
+				}
+				err := s.Relogin(context.Background(), stale)
+				if (want == nil) != (err == nil) || (want != nil && !errors.Is(err, want)) {
+					t.Fatalf("第 %d 次 Relogin 期望错误 %v, 实际为 %v", i+1, want, err)
+				}
+			}
+			if fake.logins != tc.wantLogins || len(reported) != tc.wantLogins {
+				t.Errorf("期望登录 %d 次, 实际为 %d 次 (OnLogin %d 次)", tc.wantLogins, fake.logins, len(reported))
+			}
+			if got := sessionCookie(s.Client(), libraryURL); got != tc.wantCookie {
+				t.Errorf("期望当前会话为 %s, 实际为 %q", tc.wantCookie, got)
+			}
+		})
+	}
+}
@@ -1,66 +1,81 @@
 package sso
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 
+	"seat-killer/redact"
 	"seat-killer/retry"
-
-	"github.com/hduLib/hdu/client"
-	"github.com/hduLib/hdu/sso"
 )
 
 const (
-	loginURL = "https://sso.hdu.edu.cn/login?service=https:%2F%2Fhdu.huitu.zhishulib.com%2FUser%2FIndex%2FhduCASLogin%3Fforward%3D%252FSpace%252FCategory%252Fredirect%253Fcategory_id%253D591"
+	loginURL   = "https://sso.hdu.edu.cn/login?service=https:%2F%2Fhdu.huitu.zhishulib.com%2FUser%2FIndex%2FhduCASLogin%3Fforward%3D%252FSpace%252FCategory%252Fredirect%253Fcategory_id%253D591"
+	libraryURL = "https://hdu.huitu.zhishulib.com"
 	// A more realistic User-Agent to better mimic a real browser.
 	userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/142.0.0.0 Safari/537.36 Edg/142.0.0.0"
 )
 
+// baseTransport carries every SSO and library request; see SetTransport.
+var baseTransport http.RoundTripper = http.DefaultTransport
+
+// SetTransport replaces the transport used by clients created by Login, e.g. to
+// use the tuned shared transport or add a circuit breaker. It must be called
+// before the first login.
+func SetTransport(rt http.RoundTripper) {
+	baseTransport = rt
+}
+
+// Transport returns the transport set by SetTransport.
+func Transport() http.RoundTripper {
+	return baseTransport
+}
+
 // customTransport injects a User-Agent header into each request.
 type customTransport struct {
 	http.RoundTripper
 }
 
 func (t *customTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
 	req.Header.Set("User-Agent", userAgent)
 	return t.RoundTripper.RoundTrip(req)
 }
 
-func Login(user, passwd string) (*http.Client, string, error) {
-	jar, err := cookiejar.New(nil)
+// Login performs the CAS login and returns a client carrying the library session.
+// ctx bounds the whole login, including redirects. Each call uses its own client,
+// so several accounts may log in concurrently.
+func Login(ctx context.Context, user, passwd string) (*http.Client, string, error) {
+	return login(ctx, loginURL, libraryURL, user, passwd)
+}
+
+// login logs in at casURL and returns the PHPSESSID the service at serviceURL issued.
+func login(ctx context.Context, casURL, serviceURL, user, passwd string) (*http.Client, string, error) {
+	customClient, err := newClient()
 	if err != nil {
 		return nil, "", err
 	}
 
-	// The hdu-go-lib client uses a global client. We must replace it with
-	// one that uses our custom transport (for the User-Agent) and cookie jar.
-	customClient := &http.Client{
-		Jar: jar,
-		Transport: &customTransport{
-			RoundTripper: http.DefaultTransport,
-		},
-	}
-	client.DefaultClient = customClient
-
-	// GenLoginReq will perform the login and all redirects using our custom client.
-	// The final session cookies will be stored in our jar.
-	_, err = sso.GenLoginReq(loginURL, user, passwd)
-	if err != nil {
+	if err := provider.Login(ctx, customClient, casURL, user, passwd); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, "", retry.WrapUnretryable(ctxErr)
+		}
 		return nil, "", err
 	}
 
 	// After login, find the PHPSESSID from the jar.
 	var phpSessID string
-	targetURL, _ := url.Parse("https://hdu.huitu.zhishulib.com")
-	for _, cookie := range jar.Cookies(targetURL) {
+	targetURL, _ := url.Parse(serviceURL)
+	for _, cookie := range customClient.Jar.Cookies(targetURL) {
 		if cookie.Name == "PHPSESSID" {
 			phpSessID = cookie.Value
 			break
 		}
 	}
 
+	redact.AddSecret(phpSessID)
 	if phpSessID == "" {
 		// This is a critical failure, likely due to incorrect credentials. Mark as unretryable.
 		return nil, "", retry.WrapUnretryable(errors.New("PHPSESSID not found after login, please check your credentials"))
@@ -70,9 +85,21 @@ func Login(user, passwd string) (*http.Client, string, error) {
 	return customClient, phpSessID, nil
 }
 
+// newClient returns a client with an empty cookie jar on top of the shared transport.
+func newClient() (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Jar:       jar,
+		Transport: &customTransport{RoundTripper: baseTransport},
+	}, nil
+}
+
 // ValidateCredentials attempts to log in to check if the user's credentials are valid.
 // It does not retain the session cookie, making it a pure validation function.
-func ValidateCredentials(user, passwd string) error {
-	_, _, err := Login(user, passwd)
+func ValidateCredentials(ctx context.Context, user, passwd string) error {
+	_, _, err := Login(ctx, user, passwd)
 	return err
 }
@@ -0,0 +1,127 @@
+package timesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultURL is queried when no other URL is configured: the library host whose clock decides when booking opens.
+const DefaultURL = "https://hdu.huitu.zhishulib.com/"
+
+// Sample is one request/response exchange.
+type Sample struct {
+	Sent       time.Time // local time the request was sent
+	Received   time.Time // local time the response arrived
+	ServerDate time.Time // the response's Date header, truncated to the second
+}
+
+// RTT returns the round-trip time of the exchange.
+func (s Sample) RTT() time.Duration {
+	return s.Received.Sub(s.Sent)
+}
+
+// Estimate is the measured difference between the server clock and ours.
+type Estimate struct {
+	// Offset is server time minus local time.
+	Offset time.Duration
+	// Uncertainty is the half-width of the interval the true offset lies in.
+	Uncertainty time.Duration
+	// RTT is the smallest round-trip time observed.
+	RTT     time.Duration
+	Samples int
+}
+
+// ToLocal converts an instant on the server clock to the local clock.
+func (e *Estimate) ToLocal(serverTime time.Time) time.Time {
+	return serverTime.Add(-e.Offset)
+}
+
+// Measure sends n HEAD requests to target and estimates the server clock offset
+// from their Date headers. Requests are spaced slightly more than a second apart
+// so that their send times fall on different fractions of the server's second,
+// which lets the one-second resolution of the Date header be narrowed down.
+func Measure(ctx context.Context, client *http.Client, target string, n int) (*Estimate, error) {
+	if n < 1 {
+		return nil, errors.New("timesync: at least one sample is required")
+	}
+	spacing := time.Second + time.Second/time.Duration(n)
+	samples := make([]Sample, 0, n)
+	var lastErr error
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			timer := time.NewTimer(spacing)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		sample, err := probe(ctx, client, target)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("timesync: all %d samples failed, last error: %w", n, lastErr)
+	}
+	return estimate(samples), nil
+}
+
+func probe(ctx context.Context, client *http.Client, target string) (Sample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return Sample{}, err
+	}
+	sent := time.Now()
+	resp, err := client.Do(req)
+	received := time.Now()
+	if err != nil {
+		return Sample{}, err
+	}
+	resp.Body.Close()
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return Sample{}, fmt.Errorf("timesync: response has no usable Date header: %w", err)
+	}
+	return Sample{Sent: sent, Received: received, ServerDate: date}, nil
+}
+
+// estimate combines samples NTP-style. The server stamped each Date somewhere in
+// [ServerDate, ServerDate+1s) while our clock was in [Sent, Received], so every
+// sample bounds the offset to [ServerDate-Received, ServerDate+1s-Sent]. The
+// intersection of all bounds is the estimate. If the bounds disagree (a server
+// behind a load balancer with unsynchronised clocks, say) it falls back to the
+// sample with the smallest round trip.
+func estimate(samples []Sample) *Estimate {
+	best := samples[0]
+	lo, hi := lowerBound(best), upperBound(best)
+	for _, s := range samples[1:] {
+		if s.RTT() < best.RTT() {
+			best = s
+		}
+		lo, hi = max(lo, lowerBound(s)), min(hi, upperBound(s))
+	}
+	if lo > hi {
+		lo, hi = lowerBound(best), upperBound(best)
+	}
+	return &Estimate{
+		Offset:      lo + (hi-lo)/2,
+		Uncertainty: (hi - lo) / 2,
+		RTT:         best.RTT(),
+		Samples:     len(samples),
+	}
+}
+
+func lowerBound(s Sample) time.Duration {
+	return s.ServerDate.Sub(s.Received)
+}
+
+func upperBound(s Sample) time.Duration {
+	return s.ServerDate.Add(time.Second).Sub(s.Sent)
+}
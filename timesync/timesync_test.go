@@ -0,0 +1,69 @@
+package timesync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeSample simulates an exchange with a server whose clock is offset from ours.
+func fakeSample(sent time.Time, rtt, offset time.Duration) Sample {
+	serverTime := sent.Add(rtt / 2).Add(offset)
+	return Sample{
+		Sent:       sent,
+		Received:   sent.Add(rtt),
+		ServerDate: serverTime.Truncate(time.Second),
+	}
+}
+
+func TestEstimateNarrowsToTrueOffset(t *testing.T) {
+	offset := 2300 * time.Millisecond
+	rtt := 40 * time.Millisecond
+	start := time.Date(2025, 1, 6, 19, 50, 0, 0, time.UTC)
+
+	var samples []Sample
+	for i := 0; i < 8; i++ {
+		sent := start.Add(time.Duration(i) * (time.Second + time.Second/8))
+		samples = append(samples, fakeSample(sent, rtt, offset))
+	}
+
+	got := estimate(samples)
+	if diff := got.Offset - offset; diff < -got.Uncertainty || diff > got.Uncertainty {
+		t.Fatalf("期望偏移 %s 落在 %s ± %s 内", offset, got.Offset, got.Uncertainty)
+	}
+	if got.Uncertainty > 200*time.Millisecond {
+		t.Errorf("期望误差小于 200ms, 实际为 %s", got.Uncertainty)
+	}
+	if got.RTT != rtt {
+		t.Errorf("期望 RTT 为 %s, 实际为 %s", rtt, got.RTT)
+	}
+}
+
+func TestEstimateFallsBackOnInconsistentSamples(t *testing.T) {
+	start := time.Date(2025, 1, 6, 19, 50, 0, 0, time.UTC)
+	samples := []Sample{
+		fakeSample(start, 30*time.Millisecond, 0),
+		fakeSample(start.Add(time.Second), 80*time.Millisecond, 5*time.Second),
+	}
+	got := estimate(samples)
+	if got.Offset < -time.Second || got.Offset > time.Second {
+		t.Errorf("期望回退到 RTT 最小的样本 (偏移约 0), 实际为 %s", got.Offset)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-3*time.Second).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	got, err := Measure(context.Background(), server.Client(), server.URL, 1)
+	if err != nil {
+		t.Fatalf("Measure 返回错误: %v", err)
+	}
+	if got.Offset > -2*time.Second || got.Offset < -4*time.Second {
+		t.Errorf("期望偏移约为 -3s, 实际为 %s", got.Offset)
+	}
+}
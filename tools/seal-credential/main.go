@@ -0,0 +1,52 @@
+// seal-credential stores a password in an encrypted credential file that
+// user_info.yml can reference with password_ref: "file:<path>#<entry>".
+//
+//	SEAT_KILLER_PASSPHRASE=... go run ./tools/seal-credential -entry 230001 < password.txt
+//
+// The password is read from the first line of standard input and never printed.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	"seat-killer/credential"
+)
+
+func main() {
+	path := flag.String("file", "credentials.enc", "encrypted credential file to create or update")
+	entry := flag.String("entry", "", "entry name, usually the school ID")
+	flag.Parse()
+	if *entry == "" {
+		log.Fatal("-entry is required")
+	}
+
+	passphrase, err := credential.Passphrase()
+	if err != nil {
+		log.Fatal(err)
+	}
+	entries, err := credential.ReadFile(*path, passphrase)
+	if errors.Is(err, fs.ErrNotExist) {
+		entries = make(map[string]string)
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Reading the password for %q from standard input...", *entry)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		log.Fatalf("No password read from standard input: %v", err)
+	}
+
+	entries[*entry] = password
+	if err := credential.WriteFile(*path, passphrase, entries); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Stored %q in %s. Reference it with password_ref: \"file:%s#%s\".", *entry, *path, *path, *entry)
+}
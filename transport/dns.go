@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Resolver caches DNS answers so the booking burst never waits on a lookup.
+type Resolver struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// NewResolver returns a cache that keeps answers for ttl.
+func NewResolver(ttl time.Duration) *Resolver {
+	return &Resolver{ttl: ttl, entries: make(map[string]dnsEntry)}
+}
+
+// Lookup returns the cached addresses for host, resolving it when missing or stale.
+func (r *Resolver) Lookup(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	entry, ok := r.entries[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Err: err})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(r.ttl)}
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+// PreResolve fills the cache for hosts ahead of time. Failures are returned but
+// do not prevent the remaining hosts from being resolved.
+func (r *Resolver) PreResolve(ctx context.Context, hosts []string) error {
+	var firstErr error
+	for _, host := range hosts {
+		if _, err := r.Lookup(ctx, host); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// DialContext returns a dial function that resolves through the cache and tries
+// each cached address in turn.
+func (r *Resolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := r.Lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
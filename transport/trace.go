@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing records where the time of one request went. Phases that did not happen,
+// e.g. DNS and TLS on a reused connection, stay zero.
+type Timing struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB is measured from the start of the request to the first response byte.
+	TTFB   time.Duration
+	Reused bool
+}
+
+// WithTiming returns a context whose requests report their phases into the returned Timing.
+func WithTiming(ctx context.Context) (context.Context, *Timing) {
+	t := &Timing{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.since(&t.DNS, &t.dnsStart) },
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) { t.since(&t.Connect, &t.connectStart) },
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) { t.since(&t.TLS, &t.tlsStart) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.Reused = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() { t.since(&t.TTFB, &t.start) },
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+func (t *Timing) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *Timing) since(d *time.Duration, from *time.Time) {
+	t.mu.Lock()
+	if !from.IsZero() {
+		*d = time.Since(*from)
+	}
+	t.mu.Unlock()
+}
+
+// String summarises the timing in milliseconds.
+func (t *Timing) String() string {
+	if t == nil {
+		return "no request"
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fmt.Sprintf("dns=%dms connect=%dms tls=%dms ttfb=%dms reused=%t",
+		t.DNS.Milliseconds(), t.Connect.Milliseconds(), t.TLS.Milliseconds(), t.TTFB.Milliseconds(), t.Reused)
+}
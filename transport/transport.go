@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Config tunes the shared HTTP transport.
+type Config struct {
+	// RequestTimeout bounds each request from dial to the end of the body. Zero disables it.
+	RequestTimeout      time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	// IdleConnTimeout is how long an idle keep-alive connection is kept in the pool.
+	IdleConnTimeout     time.Duration
+	MaxIdleConnsPerHost int
+	DisableHTTP2        bool
+	// Proxy is an optional outbound proxy URL (http, https or socks5). Empty uses the environment.
+	Proxy string
+	// DNSCacheTTL caches resolved addresses for this long. Zero disables the cache.
+	DNSCacheTTL time.Duration
+}
+
+// New builds a transport with keep-alives, a tuned connection pool and, when
+// configured, a DNS cache and an outbound proxy. The returned resolver is nil
+// when DNS caching is disabled.
+func New(cfg Config) (http.RoundTripper, *Resolver, error) {
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}
+	base := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   !cfg.DisableHTTP2,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+	}
+	if cfg.DisableHTTP2 {
+		// A non-nil empty map turns off the automatic HTTP/2 upgrade.
+		base.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid proxy URL %q: %w", cfg.Proxy, err)
+		}
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	var resolver *Resolver
+	if cfg.DNSCacheTTL > 0 {
+		resolver = NewResolver(cfg.DNSCacheTTL)
+		base.DialContext = resolver.DialContext(dialer)
+	}
+
+	var rt http.RoundTripper = base
+	if cfg.RequestTimeout > 0 {
+		rt = &timeoutTransport{base: rt, timeout: cfg.RequestTimeout}
+	}
+	return rt, resolver, nil
+}
+
+// timeoutTransport bounds every request, including reading its body.
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// Warm opens up to n connections to target in parallel so that the same number
+// of concurrent requests later find a hot connection in the pool.
+func Warm(ctx context.Context, client *http.Client, target string, n int) error {
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			errs <- err
+		}()
+	}
+	var firstErr error
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	rt, _, err := New(Config{RequestTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = (&http.Client{Transport: rt}).Get(server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望超时错误, 实际为 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("请求超时未生效, 耗时 %v", elapsed)
+	}
+}
+
+func TestTimeoutKeepsBodyReadable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	rt, _, err := New(Config{RequestTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: rt}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("读取响应失败: %q, %v", body, err)
+	}
+}
+
+func TestInvalidProxy(t *testing.T) {
+	if _, _, err := New(Config{Proxy: "://bad"}); err == nil {
+		t.Error("期望无效代理地址返回错误")
+	}
+}
+
+func TestResolverCaches(t *testing.T) {
+	r := NewResolver(time.Minute)
+	r.entries["seat.example"] = dnsEntry{addrs: []string{"127.0.0.1"}, expires: time.Now().Add(time.Minute)}
+
+	addrs, err := r.Lookup(context.Background(), "seat.example")
+	if err != nil || len(addrs) != 1 || addrs[0] != "127.0.0.1" {
+		t.Fatalf("期望命中缓存, 实际为 %v, %v", addrs, err)
+	}
+}
+
+func TestTimingAndWarm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rt, _, err := New(Config{MaxIdleConnsPerHost: 2, IdleConnTimeout: time.Minute, DNSCacheTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rt}
+	if err := Warm(context.Background(), client, server.URL, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, timing := WithTiming(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !timing.Reused {
+		t.Error("期望复用预热的连接")
+	}
+	if timing.TTFB <= 0 {
+		t.Errorf("期望记录首字节时间, 实际为 %s", timing)
+	}
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -23,9 +24,14 @@ type ResponseData struct {
 }
 
 // GetUserInfo fetches user information after a successful login.
-func GetUserInfo(client *http.Client) (*UserInfo, error) {
+func GetUserInfo(ctx context.Context, client *http.Client) (*UserInfo, error) {
 	// The searchSeats endpoint requires a POST request, even for just getting user info.
-	resp, err := client.Post(userInfoURL, "application/x-www-form-urlencoded;charset=UTF-8", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, userInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=UTF-8")
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request to user info url: %w", err)
 	}